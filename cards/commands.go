@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example.com/client"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"sort"
+	"strings"
+)
+
+func createCommand(fs *flag.FlagSet) action {
+	cards := fs.String("cards", "", "codes of the cards to put in the deck, as in AS,KD,10H (a full deck by default)")
+	shuffled := fs.Bool("shuffled", false, "shuffles the deck")
+	visibility := fs.String("visibility", "", "public, owner, facedown or hidden")
+	rules := fs.String("rules", "", "the type of game whose rules govern the deck")
+	ttl := fs.Duration("ttl", 0, "how long the deck may sit idle before being evicted")
+
+	return func(ctx context.Context, cli *cli, _ []string) error {
+		options := client.CreateOptions{
+			Shuffled:   *shuffled,
+			Visibility: client.Visibility(*visibility),
+			Rules:      *rules,
+			TTL:        *ttl,
+		}
+		if *cards != "" {
+			options.Cards = strings.Split(*cards, ",")
+		}
+
+		created, err := cli.decks.CreateDeck(ctx, options)
+		if err != nil {
+			return err
+		}
+		return cli.print(created, func(w io.Writer) {
+			fmt.Fprintf(w, "%v\n", created.ID)
+			fmt.Fprintf(w, "%d cards, %v\n", created.Remaining, shuffledOrNot(created.Shuffled))
+		})
+	}
+}
+
+func shuffledOrNot(shuffled bool) string {
+	if shuffled {
+		return "shuffled"
+	}
+	return "in order"
+}
+
+func openCommand(fs *flag.FlagSet) action {
+	return onDeck(func(ctx context.Context, cli *cli, id uuid.UUID) error {
+		d, err := cli.decks.OpenDeck(ctx, id)
+		if err != nil {
+			return err
+		}
+		return cli.print(d, func(w io.Writer) {
+			fmt.Fprintf(w, "%v (version %d, %v)\n", d.ID, d.Version, d.Visibility)
+			if d.Owner != "" {
+				fmt.Fprintf(w, "owned by %v", d.Owner)
+				if len(d.SharedWith) > 0 {
+					fmt.Fprintf(w, ", shared with %v", strings.Join(d.SharedWith, ", "))
+				}
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%d cards, %v: %v\n", d.Remaining, shuffledOrNot(d.Shuffled), cli.cards(d.Cards, &d.Remaining))
+
+			names := []string{}
+			for name := range d.Piles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				pile := d.Piles[name]
+				fmt.Fprintf(w, "%v (%v, %v): %v\n", name, pile.Owner, pile.Visibility, cli.cards(pile.Cards, pile.Count))
+			}
+		})
+	})
+}
+
+// faces when they can be seen, backs when they can only be counted and a
+// question mark when not even that
+func (cli *cli) cards(cards []client.Card, count *int) string {
+	if len(cards) > 0 {
+		return renderCards(cards, cli.color)
+	}
+	if count != nil {
+		return renderBacks(*count)
+	}
+	return "?"
+}
+
+func drawCommand(fs *flag.FlagSet) action {
+	count := fs.Int("count", 1, "how many cards to draw")
+	pile := fs.String("pile", "", "keeps the drawn cards in a pile of yours")
+	pileVisibility := fs.String("pile-visibility", "", "who sees the pile, only you by default")
+	allowPartial := fs.Bool("allow-partial", false, "settles for whatever is left when there aren't enough cards")
+	idempotencyKey := fs.String("idempotency-key", "", "makes retrying safe")
+
+	return onDeck(func(ctx context.Context, cli *cli, id uuid.UUID) error {
+		options := client.DrawOptions{
+			Count:          *count,
+			Pile:           *pile,
+			PileVisibility: client.Visibility(*pileVisibility),
+			AllowPartial:   *allowPartial,
+			IdempotencyKey: *idempotencyKey,
+		}
+		cards, err := cli.decks.Draw(ctx, id, options)
+		if err != nil {
+			return err
+		}
+		return cli.print(cards, func(w io.Writer) {
+			fmt.Fprintln(w, renderCards(cards, cli.color))
+		})
+	})
+}
+
+// one card at a time to each player in turn, as dealers do, into a pile
+// named after and owned by the player. Nothing is dealt unless everyone can
+// be served
+func dealCommand(fs *flag.FlagSet) action {
+	players := fs.String("players", "", "who to deal to, as in alice,bob")
+	count := fs.Int("count", 1, "how many cards each player gets")
+	pileVisibility := fs.String("pile-visibility", "", "who sees the piles dealt into, only the player dealt to by default")
+
+	return onDeck(func(ctx context.Context, cli *cli, id uuid.UUID) error {
+		names := []string{}
+		for _, name := range strings.Split(*players, ",") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return errors.New("Nobody to deal to, see --players")
+		}
+		if *count < 1 {
+			return fmt.Errorf("Cannot deal %d cards", *count)
+		}
+
+		options := client.DealOptions{Players: names, Count: *count, PileVisibility: client.Visibility(*pileVisibility)}
+		d, err := cli.decks.Deal(ctx, id, options)
+		if err != nil {
+			return err
+		}
+
+		hands := map[string]client.Pile{}
+		for _, name := range names {
+			hands[name] = d.Piles[name]
+		}
+		return cli.print(hands, func(w io.Writer) {
+			width := 0
+			for _, name := range names {
+				width = max(width, len(name))
+			}
+			// the hands of the others can only be counted, unless dealt face up
+			for _, name := range names {
+				fmt.Fprintf(w, "%-*v  %v\n", width, name, cli.cards(hands[name].Cards, hands[name].Count))
+			}
+		})
+	})
+}
+
+func shuffleCommand(fs *flag.FlagSet) action {
+	return onDeck(func(ctx context.Context, cli *cli, id uuid.UUID) error {
+		shuffled, err := cli.decks.Shuffle(ctx, id)
+		if err != nil {
+			return err
+		}
+		return cli.print(shuffled, func(w io.Writer) {
+			fmt.Fprintf(w, "%d cards, %v\n", shuffled.Remaining, shuffledOrNot(shuffled.Shuffled))
+		})
+	})
+}
+
+// snapshots are JSON whatever the output mode since that's what importing
+// them takes
+func exportCommand(fs *flag.FlagSet) action {
+	return onDeck(func(ctx context.Context, cli *cli, id uuid.UUID) error {
+		snapshot, err := cli.decks.Export(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cli.out, "%s\n", snapshot)
+		return err
+	})
+}
@@ -0,0 +1,16 @@
+module example.com/cards
+
+go 1.22
+
+replace example.com/client => ../client
+
+replace example.com/deck => ../deck
+
+require (
+	example.com/client v0.0.0-00010101000000-000000000000
+	example.com/deck v0.0.0-00010101000000-000000000000
+	github.com/google/uuid v1.6.0
+	golang.org/x/term v0.19.0
+)
+
+require golang.org/x/sys v0.19.0 // indirect
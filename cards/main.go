@@ -0,0 +1,273 @@
+// cards is a command line client of the card games engine. It talks to a
+// server unless told to work offline, in which case decks are kept in a
+// local file and handled by package deck directly
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example.com/client"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const usage = `usage: cards <command> [operands] [flags]
+
+commands:
+  create    creates a deck
+  open      shows what can be seen of a deck
+  draw      draws cards from a deck
+  deal      deals cards round robin into a pile per player
+  shuffle   shuffles what's left of a deck
+  export    writes a deck down in full as a snapshot
+  play      plays a game hosted on the server in the terminal
+
+flags understood by every command:
+  --server URL    the server to talk to ($CARDS_SERVER, http://localhost:8000)
+  --api-key KEY   authenticates with an api key ($CARDS_API_KEY)
+  --token JWT     authenticates with a bearer token ($CARDS_TOKEN)
+  --player NAME   who is asking when nobody is authenticated ($CARDS_PLAYER)
+  --json          prints what the API answers instead of cards
+  --no-color      prints suits uncolored, as does setting $NO_COLOR
+  --offline       works on the decks kept in --state instead of a server
+  --state FILE    where offline decks are kept ($CARDS_STATE)
+
+run cards <command> --help for the flags of a command
+`
+
+// what every command does once its flags are parsed. operands are what's
+// left of the command line, as many as the command said it takes
+type action func(ctx context.Context, cli *cli, operands []string) error
+
+type command struct {
+	// as shown in errors, i.e. <deck id>
+	operands string
+	min, max int
+	flags    func(fs *flag.FlagSet) action
+}
+
+var commands = map[string]command{
+	"create":  {"", 0, 0, createCommand},
+	"open":    {"<deck id>", 1, 1, openCommand},
+	"draw":    {"<deck id>", 1, 1, drawCommand},
+	"deal":    {"<deck id>", 1, 1, dealCommand},
+	"shuffle": {"<deck id>", 1, 1, shuffleCommand},
+	"export":  {"<deck id>", 1, 1, exportCommand},
+	"play":    {"<game type> [game id]", 1, 2, playCommand},
+}
+
+// a command line that makes no sense, as opposed to a command that failed
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{fmt.Sprintf(format, args...)}
+}
+
+// most commands work on a single deck
+func onDeck(act func(ctx context.Context, cli *cli, id uuid.UUID) error) action {
+	return func(ctx context.Context, cli *cli, operands []string) error {
+		id, err := uuid.Parse(operands[0])
+		if err != nil {
+			return usageErrorf("Invalid deck id %q", operands[0])
+		}
+		return act(ctx, cli, id)
+	}
+}
+
+// the decks the commands work on, be they on a server (*client.Client) or
+// offline (*offlineDecks)
+type decks interface {
+	CreateDeck(ctx context.Context, options client.CreateOptions) (client.CreatedDeck, error)
+	OpenDeck(ctx context.Context, id uuid.UUID) (client.Deck, error)
+	Draw(ctx context.Context, id uuid.UUID, options client.DrawOptions) ([]client.Card, error)
+	Deal(ctx context.Context, id uuid.UUID, options client.DealOptions) (client.Deck, error)
+	Shuffle(ctx context.Context, id uuid.UUID) (client.CreatedDeck, error)
+	Export(ctx context.Context, id uuid.UUID) (json.RawMessage, error)
+}
+
+type cli struct {
+	decks decks
+	// games are only ever played on a server
+	server      *client.Client
+	in          io.Reader
+	out         io.Writer
+	json        bool
+	color       bool
+	interactive bool
+	player      string
+	offline     bool
+	// whether the server is told who's asking by credentials rather than
+	// taking the player's word for it
+	authenticated bool
+}
+
+// flags shared by every command
+type settings struct {
+	server  string
+	apiKey  string
+	token   string
+	player  string
+	json    bool
+	noColor bool
+	offline bool
+	state   string
+}
+
+func registerSettings(fs *flag.FlagSet) *settings {
+	s := &settings{}
+	fs.StringVar(&s.server, "server", envOr("CARDS_SERVER", "http://localhost:8000"), "the server to talk to")
+	fs.StringVar(&s.apiKey, "api-key", os.Getenv("CARDS_API_KEY"), "authenticates with an api key")
+	fs.StringVar(&s.token, "token", os.Getenv("CARDS_TOKEN"), "authenticates with a bearer token")
+	fs.StringVar(&s.player, "player", os.Getenv("CARDS_PLAYER"), "who is asking when nobody is authenticated")
+	fs.BoolVar(&s.json, "json", false, "prints what the API answers instead of cards")
+	fs.BoolVar(&s.noColor, "no-color", os.Getenv("NO_COLOR") != "", "prints suits uncolored")
+	fs.BoolVar(&s.offline, "offline", false, "works on the decks kept in --state instead of a server")
+	fs.StringVar(&s.state, "state", envOr("CARDS_STATE", defaultStatePath()), "where offline decks are kept")
+	return s
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "cards", "decks.json")
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// returns the exit code: 2 for a command line that makes no sense and 1 for
+// a command that failed
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("cards "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	s := registerSettings(fs)
+	act := cmd.flags(fs)
+
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+
+	if len(positional) < cmd.min || len(positional) > cmd.max {
+		fmt.Fprintf(stderr, "usage: cards %v %v [flags]\n", name, cmd.operands)
+		return 2
+	}
+
+	c := &cli{
+		decks:         s.decks(),
+		server:        s.client(),
+		in:            stdin,
+		out:           stdout,
+		json:          s.json,
+		color:         !s.noColor && isTerminal(stdout),
+		interactive:   isTerminal(stdout),
+		player:        s.player,
+		offline:       s.offline,
+		authenticated: s.apiKey != "" || s.token != "",
+	}
+	err = act(ctx, c, positional)
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintln(stderr, usageErr)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cards %v: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// flags may come before or after the deck id, as in cards draw <id> --count 2
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func (s *settings) decks() decks {
+	if s.offline {
+		return &offlineDecks{s.state, s.player}
+	}
+	return s.client()
+}
+
+func (s *settings) client() *client.Client {
+	options := []client.Option{}
+	if s.apiKey != "" {
+		options = append(options, client.WithAPIKey(s.apiKey))
+	}
+	if s.token != "" {
+		options = append(options, client.WithBearerToken(s.token))
+	}
+	if s.player != "" {
+		options = append(options, client.WithPlayer(s.player))
+	}
+	return client.New(s.server, options...)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// value as JSON with --json, otherwise whatever pretty prints
+func (c *cli) print(value any, pretty func(w io.Writer)) error {
+	if !c.json {
+		pretty(c.out)
+		return nil
+	}
+
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
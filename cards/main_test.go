@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"example.com/client"
+	"example.com/deck"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runs cards with args and returns what it printed
+func cards(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestOffline(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "decks.json")
+	offline := func(args ...string) []string {
+		return append(args, "--offline", "--state", state, "--player", "alice")
+	}
+
+	stdout, stderr, code := cards(t, offline("create", "--cards", "AS,2S,3S,KH,10H", "--json")...)
+	if code != 0 {
+		t.Fatalf("Expected to create a deck, found %v: %v", code, stderr)
+	}
+	var created client.CreatedDeck
+	if err := json.Unmarshal([]byte(stdout), &created); err != nil || created.Remaining != 5 {
+		t.Fatalf("Expected the created deck as JSON, found %v (%v)", stdout, err)
+	}
+	id := created.ID.String()
+
+	t.Run("draws with suit symbols", func(t *testing.T) {
+		stdout, stderr, code := cards(t, offline("draw", id, "--count", "2")...)
+		if code != 0 || stdout != "K♥ 10♥\n" {
+			t.Errorf("Expected to draw K♥ 10♥, found %q (%v)", stdout, stderr)
+		}
+	})
+
+	t.Run("deals round robin into piles", func(t *testing.T) {
+		stdout, stderr, code := cards(t, offline("deal", id, "--players", "bob,carol")...)
+		backs := fmt.Sprintf("bob    %v\ncarol  %v\n", deck.CardBack, deck.CardBack)
+		if code != 0 || stdout != backs {
+			t.Errorf("Expected alice to only count the cards she dealt, found %q (%v)", stdout, stderr)
+		}
+	})
+
+	t.Run("keeps decks between runs", func(t *testing.T) {
+		stdout, _, code := cards(t, offline("open", id, "--json")...)
+		var d client.Deck
+		if err := json.Unmarshal([]byte(stdout), &d); err != nil || code != 0 {
+			t.Fatalf("Expected the deck as JSON, found %v (%v)", stdout, err)
+		}
+		bob := d.Piles["bob"]
+		if d.Remaining != 1 || len(d.Piles) != 2 || bob.Owner != "bob" || bob.Cards != nil || *bob.Count != 1 || d.Version != 4 {
+			t.Errorf("Expected the draws to be remembered, found %+v", d)
+		}
+	})
+
+	t.Run("fails to draw more than is left", func(t *testing.T) {
+		_, stderr, code := cards(t, offline("draw", id, "--count", "5")...)
+		if code != 1 || !strings.Contains(stderr, "Cannot draw 5") {
+			t.Errorf("Expected drawing 5 cards to fail, found %v: %v", code, stderr)
+		}
+	})
+
+	t.Run("settles for what is left when allowed to", func(t *testing.T) {
+		stdout, _, code := cards(t, offline("draw", id, "--count", "5", "--allow-partial")...)
+		if code != 0 || stdout != "A♠\n" {
+			t.Errorf("Expected to draw the last card, found %q", stdout)
+		}
+	})
+
+	t.Run("refuses to export the hands of other players", func(t *testing.T) {
+		if _, stderr, code := cards(t, offline("export", id)...); code != 1 || !strings.Contains(stderr, "hides cards") {
+			t.Errorf("Expected alice not to export what was dealt to bob and carol, found %v: %v", code, stderr)
+		}
+	})
+
+	t.Run("exports a snapshot that restores", func(t *testing.T) {
+		stdout, _, _ := cards(t, offline("create", "--json")...)
+		var created client.CreatedDeck
+		json.Unmarshal([]byte(stdout), &created)
+		cards(t, offline("draw", created.ID.String(), "--count", "2")...)
+
+		stdout, stderr, code := cards(t, offline("export", created.ID.String())...)
+		snapshot, err := deck.ParseSnapshot([]byte(stdout))
+		if code != 0 || err != nil {
+			t.Fatalf("Expected a snapshot, found %v (%v %v)", stdout, err, stderr)
+		}
+		if _, err := snapshot.Restore(); err != nil {
+			t.Errorf("Expected the snapshot to restore, found %v", err)
+		}
+	})
+
+	t.Run("keeps others out", func(t *testing.T) {
+		args := []string{"open", id, "--offline", "--state", state, "--player", "mallory"}
+		if _, stderr, code := cards(t, args...); code != 1 {
+			t.Errorf("Expected mallory to be kept out, found %v: %v", code, stderr)
+		}
+	})
+}
+
+func TestUsage(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no command", []string{}, 2},
+		{"help", []string{"help"}, 0},
+		{"unknown command", []string{"juggle"}, 2},
+		{"missing deck id", []string{"draw"}, 2},
+		{"invalid deck id", []string{"draw", "nope"}, 2},
+		{"unknown flag", []string{"create", "--colour"}, 2},
+		{"missing game type", []string{"play"}, 2},
+		{"invalid game id", []string{"play", "highcard", "nope"}, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, stderr, code := cards(t, c.args...); code != c.code {
+				t.Errorf("Expected exit code %v, found %v: %v", c.code, code, stderr)
+			}
+		})
+	}
+}
+
+func TestOnline(t *testing.T) {
+	var deals []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		deals = append(deals, r.URL.Path+" "+query.Get("players")+" "+query.Get("count")+" "+query.Get("pile_visibility")+" "+r.Header.Get("X-API-Key"))
+		w.Write([]byte(`{"deck_id":"67e55044-10b1-426f-9247-bb680e5fe0c8","remaining":0,"visibility":"public","version":5,
+			"piles":{"bob":{"owner":"bob","visibility":"owner","count":2},"carol":{"owner":"carol","visibility":"public","count":2,"cards":[{"value":"QUEEN","suit":"DIAMONDS","code":"QD"},{"value":"KING","suit":"CLUBS","code":"KC"}]}}}`))
+	}))
+	defer server.Close()
+
+	args := []string{"deal", "67e55044-10b1-426f-9247-bb680e5fe0c8", "--players", "bob,carol", "--count", "2", "--pile-visibility", "public"}
+	stdout, stderr, code := cards(t, append(args, "--server", server.URL, "--api-key", "alices-key")...)
+	expected := fmt.Sprintf("bob    %v %v\ncarol  Q♦ K♣\n", deck.CardBack, deck.CardBack)
+	if code != 0 || stdout != expected {
+		t.Errorf("Expected both to be dealt 2 cards, found %q (%v)", stdout, stderr)
+	}
+
+	if strings.Join(deals, ",") != "/v1/decks/67e55044-10b1-426f-9247-bb680e5fe0c8/deals bob,carol 2 public alices-key" {
+		t.Errorf("Expected a single deal, found %v", deals)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example.com/client"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+var ErrDeckNotFound = errors.New("There's no such deck")
+
+// decks kept in a file between runs, written down as snapshots so that they
+// can be imported into a server later on. They answer like a server would,
+// minus what only makes sense on a server (rules, ttl, idempotency keys)
+type offlineDecks struct {
+	path   string
+	player string
+}
+
+func (o *offlineDecks) load() (map[uuid.UUID]deck.Deck, error) {
+	return deck.ReadSnapshots(o.path)
+}
+
+func (o *offlineDecks) save(decks map[uuid.UUID]deck.Deck) error {
+	return deck.WriteSnapshots(o.path, decks)
+}
+
+// the deck with every other deck, as long as player may access it
+func (o *offlineDecks) find(id uuid.UUID) (deck.Deck, map[uuid.UUID]deck.Deck, error) {
+	decks, err := o.load()
+	if err != nil {
+		return deck.NewEmptyDeck(), decks, err
+	}
+
+	d, ok := decks[id]
+	if !ok {
+		return d, decks, fmt.Errorf("%w: %v in %v", ErrDeckNotFound, id, o.path)
+	}
+	if !d.IsAccessibleBy(o.player) {
+		msg := fmt.Sprintf("%v may not access deck %v", o.player, d.Guid)
+		return d, decks, errors.New(msg)
+	}
+	return d, decks, nil
+}
+
+// lets change do its thing and saves the deck unless change failed
+func (o *offlineDecks) update(id uuid.UUID, change func(d *deck.Deck) error) (deck.Deck, error) {
+	d, decks, err := o.find(id)
+	if err != nil {
+		return d, err
+	}
+
+	if err := change(&d); err != nil {
+		return d, err
+	}
+	decks[id] = d
+	return d, o.save(decks)
+}
+
+func (o *offlineDecks) CreateDeck(ctx context.Context, options client.CreateOptions) (client.CreatedDeck, error) {
+	d := deck.NewDefaultDeck()
+	if len(options.Cards) > 0 {
+		cards := []deck.Card{}
+		for _, code := range options.Cards {
+			card, err := deck.ParseCard(code)
+			if err != nil {
+				return client.CreatedDeck{}, err
+			}
+			cards = append(cards, card)
+		}
+		d = deck.NewDeck(cards)
+	}
+
+	if options.Visibility != "" {
+		visibility, err := deck.ParseVisibility(strings.ToLower(string(options.Visibility)))
+		if err != nil {
+			return client.CreatedDeck{}, err
+		}
+		d.Visibility = visibility
+	}
+	if options.Shuffled {
+		d.Shuffle()
+	}
+	d.Owner = o.player
+	d.RecordCreate(o.player)
+
+	decks, err := o.load()
+	if err != nil {
+		return client.CreatedDeck{}, err
+	}
+	decks[d.Guid] = d
+	return intoCreatedDeck(d), o.save(decks)
+}
+
+func (o *offlineDecks) OpenDeck(ctx context.Context, id uuid.UUID) (client.Deck, error) {
+	d, _, err := o.find(id)
+	if err != nil {
+		return client.Deck{}, err
+	}
+	return intoDeck(d, o.player), nil
+}
+
+func (o *offlineDecks) Draw(ctx context.Context, id uuid.UUID, options client.DrawOptions) ([]client.Card, error) {
+	drawn := []deck.Card{}
+	_, err := o.update(id, func(d *deck.Deck) error {
+		if err := checkVersion(d, options.IfVersion); err != nil {
+			return err
+		}
+
+		count := options.Count
+		if count == 0 {
+			count = 1
+		}
+		if options.AllowPartial && count > d.RemainingCardCount() {
+			count = d.RemainingCardCount()
+		}
+
+		visibility, err := pileVisibility(options.PileVisibility)
+		if err != nil {
+			return err
+		}
+		drawn, err = d.DrawFor(o.player, options.Pile, visibility, count)
+		if err != nil {
+			return err
+		}
+		d.RecordDraw(o.player, options.Pile, drawn)
+		return nil
+	})
+	return intoCards(drawn), err
+}
+
+func (o *offlineDecks) Deal(ctx context.Context, id uuid.UUID, options client.DealOptions) (client.Deck, error) {
+	d, err := o.update(id, func(d *deck.Deck) error {
+		if err := checkVersion(d, options.IfVersion); err != nil {
+			return err
+		}
+
+		count := options.Count
+		if count == 0 {
+			count = 1
+		}
+		visibility, err := pileVisibility(options.PileVisibility)
+		if err != nil {
+			return err
+		}
+		_, err = d.Deal(o.player, options.Players, count, visibility)
+		return err
+	})
+	return intoDeck(d, o.player), err
+}
+
+func checkVersion(d *deck.Deck, version *int) error {
+	if version != nil && *version != d.Version {
+		msg := fmt.Sprintf("Deck %v is at version %d", d.Guid, d.Version)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// only the owner sees piles by default
+func pileVisibility(visibility client.Visibility) (deck.Visibility, error) {
+	if visibility == "" {
+		return deck.OwnerOnly, nil
+	}
+	return deck.ParseVisibility(strings.ToLower(string(visibility)))
+}
+
+func (o *offlineDecks) Shuffle(ctx context.Context, id uuid.UUID) (client.CreatedDeck, error) {
+	d, err := o.update(id, func(d *deck.Deck) error {
+		d.Shuffle()
+		d.RecordShuffle(o.player)
+		return nil
+	})
+	return intoCreatedDeck(d), err
+}
+
+// only the owner may export a deck and only when it hides nothing from
+// them, as on a server
+func (o *offlineDecks) Export(ctx context.Context, id uuid.UUID) (json.RawMessage, error) {
+	d, _, err := o.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Owner != "" && d.Owner != o.player {
+		msg := fmt.Sprintf("Only %v may export deck %v", d.Owner, d.Guid)
+		return nil, errors.New(msg)
+	}
+	if d.HidesCardsFrom(o.player) {
+		msg := fmt.Sprintf("Deck %v hides cards from %v", d.Guid, o.player)
+		return nil, errors.New(msg)
+	}
+	return json.MarshalIndent(d.Snapshot(), "", "  ")
+}
+
+func intoCreatedDeck(d deck.Deck) client.CreatedDeck {
+	return client.CreatedDeck{
+		ID:        d.Guid,
+		Shuffled:  d.IsShuffled(),
+		Remaining: d.RemainingCardCount(),
+	}
+}
+
+// only what player is allowed to see, like the server's open deck
+func intoDeck(d deck.Deck, player string) client.Deck {
+	var cards []client.Card
+	if d.FacesVisibleTo(player) {
+		cards = intoCards(d.Cards)
+	}
+
+	piles := map[string]client.Pile{}
+	for name, pile := range d.Piles {
+		view := pile.ViewFor(player)
+		var pileCards []client.Card
+		if view.Cards != nil {
+			pileCards = intoCards(view.Cards)
+		}
+		piles[name] = client.Pile{
+			Owner:      view.Owner,
+			Visibility: client.Visibility(view.Visibility.String()),
+			Count:      view.Count,
+			Cards:      pileCards,
+		}
+	}
+
+	return client.Deck{
+		ID:         d.Guid,
+		Shuffled:   d.IsShuffled(),
+		Remaining:  d.RemainingCardCount(),
+		Owner:      d.Owner,
+		SharedWith: d.SharedWith,
+		Visibility: client.Visibility(d.Visibility.String()),
+		Cards:      cards,
+		Piles:      piles,
+		Version:    d.Version,
+	}
+}
+
+func intoCards(cards []deck.Card) []client.Card {
+	clientCards := []client.Card{}
+	for _, card := range cards {
+		clientCards = append(clientCards, client.Card{
+			Value: card.Rank.String(),
+			Suit:  card.Suit.String(),
+			Code:  card.Code(),
+		})
+	}
+	return clientCards
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example.com/client"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the part of the client a game is played with, one per seat
+type games interface {
+	ViewGame(ctx context.Context, gameType string, id uuid.UUID) (client.View, error)
+	Act(ctx context.Context, gameType string, id uuid.UUID, action client.Action) (client.View, error)
+}
+
+// a game being played at the keyboard. The engine plays the bots' seats
+// with whatever legal action comes to mind
+type session struct {
+	gameType string
+	id       uuid.UUID
+	you      games
+	bots     map[string]games
+}
+
+func playCommand(fs *flag.FlagSet) action {
+	players := fs.String("players", "you,engine", "who sits at a new game, as in alice,bob")
+	bots := fs.String("bots", "", "the seats the engine plays, every seat but yours in a new game unless authenticated")
+	refresh := fs.Duration("refresh", time.Second, "how often to look for moves made by others")
+
+	return func(ctx context.Context, cli *cli, operands []string) error {
+		if cli.offline {
+			return errors.New("Games are only played on a server")
+		}
+		if cli.json {
+			return errors.New("Games are played in the terminal, not in JSON")
+		}
+		gameType := operands[0]
+
+		var view client.View
+		var seats []string
+		if len(operands) == 2 {
+			id, err := uuid.Parse(operands[1])
+			if err != nil {
+				return usageErrorf("Invalid game id %q", operands[1])
+			}
+			if cli.player == "" {
+				return usageErrorf("Who are you at this game? see --player")
+			}
+			view, err = cli.server.As(cli.player).ViewGame(ctx, gameType, id)
+			if err != nil {
+				return err
+			}
+			seats = splitNames(*bots)
+			if err := checkBots(cli, seats); err != nil {
+				return err
+			}
+		} else {
+			names := splitNames(*players)
+			if len(names) == 0 {
+				return usageErrorf("Nobody to play with, see --players")
+			}
+			you := cli.player
+			if you == "" {
+				you = names[0]
+			}
+			if !slices.Contains(names, you) {
+				return usageErrorf("%v has no seat at the game, see --players", you)
+			}
+
+			seats = splitNames(*bots)
+			if *bots == "" && !cli.authenticated {
+				seats = slices.DeleteFunc(slices.Clone(names), func(name string) bool { return name == you })
+			}
+			if err := checkBots(cli, seats); err != nil {
+				return err
+			}
+
+			var err error
+			view, err = cli.server.As(you).CreateGame(ctx, gameType, names...)
+			if err != nil {
+				return err
+			}
+		}
+
+		s := &session{gameType, view.ID, cli.server.As(view.Player), map[string]games{}}
+		for _, seat := range seats {
+			if seat == view.Player {
+				return usageErrorf("%v cannot be played by the engine and at the keyboard", seat)
+			}
+			s.bots[seat] = cli.server.As(seat)
+		}
+		return cli.play(ctx, s, view, *refresh)
+	}
+}
+
+// bots act as the player of their seat, which only a server with
+// authentication turned off takes a client's word for. An authenticated one
+// would need their credentials, which only they have
+func checkBots(cli *cli, seats []string) error {
+	if cli.authenticated && len(seats) > 0 {
+		msg := fmt.Sprintf("The engine cannot play %v on an authenticated server, they have to join with their own credentials", strings.Join(seats, ","))
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func splitNames(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// redraws the table after every key and every refresh until the game is over
+// or the player quits. The final table stays on screen
+func (c *cli) play(ctx context.Context, s *session, view client.View, refresh time.Duration) error {
+	restore, err := rawMode(c.in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if c.interactive {
+		io.WriteString(c.out, hideCursor)
+		defer io.WriteString(c.out, showCursor)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	keys := readKeys(c.in, done)
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	t := &table{}
+	t.update(view)
+	for {
+		if err := s.moveBots(ctx, t); err != nil {
+			t.message = err.Error()
+		}
+		c.draw(t.render(c.color))
+		if t.view.IsFinished() {
+			return nil
+		}
+		t.message = ""
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.refresh(ctx, t)
+		case k, ok := <-keys:
+			if !ok || k.key == keyQuit {
+				return nil
+			}
+			s.press(ctx, t, k)
+		}
+	}
+}
+
+func (s *session) press(ctx context.Context, t *table, k keypress) {
+	switch k.key {
+	case keyLeft:
+		t.move(-1)
+	case keyRight:
+		t.move(1)
+	case keyRefresh:
+		s.refresh(ctx, t)
+	case keyPick:
+		if !t.pick(k.index) {
+			t.message = fmt.Sprintf("There's no action %d", k.index+1)
+			return
+		}
+		s.act(ctx, t)
+	case keyPlay:
+		s.act(ctx, t)
+	}
+}
+
+func (s *session) act(ctx context.Context, t *table) {
+	action, ok := t.selected()
+	if !ok {
+		t.message = fmt.Sprintf("It's %v's turn", t.view.Turn)
+		return
+	}
+	view, err := s.you.Act(ctx, s.gameType, s.id, action)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.update(view)
+}
+
+func (s *session) refresh(ctx context.Context, t *table) {
+	view, err := s.you.ViewGame(ctx, s.gameType, s.id)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.update(view)
+}
+
+// plays every bot whose turn it is, one after the other, until the turn
+// comes to someone at a keyboard
+func (s *session) moveBots(ctx context.Context, t *table) error {
+	for !t.view.IsFinished() {
+		bot, ok := s.bots[t.view.Turn]
+		if !ok {
+			return nil
+		}
+
+		view, err := bot.ViewGame(ctx, s.gameType, s.id)
+		if err != nil {
+			return err
+		}
+		if len(view.Legal) == 0 {
+			msg := fmt.Sprintf("%v has nothing to play", t.view.Turn)
+			return errors.New(msg)
+		}
+		action := view.Legal[rand.IntN(len(view.Legal))]
+		if _, err := bot.Act(ctx, s.gameType, s.id, action); err != nil {
+			return err
+		}
+
+		if view, err = s.you.ViewGame(ctx, s.gameType, s.id); err != nil {
+			return err
+		}
+		t.update(view)
+	}
+	return nil
+}
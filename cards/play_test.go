@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"example.com/client"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a game of taking turns laying cards on the table until hands are empty,
+// just enough of the server for cards play to be driven through it
+type fakeGame struct {
+	mu      sync.Mutex
+	id      uuid.UUID
+	players []string
+	hands   map[string][]string
+	table   []string
+	turn    int
+	moves   []string
+}
+
+func newFakeGame() *fakeGame {
+	return &fakeGame{id: uuid.New(), hands: map[string][]string{}}
+}
+
+func (g *fakeGame) view(player string) client.View {
+	v := client.View{
+		ID:      g.id,
+		Type:    "laydown",
+		Player:  player,
+		Players: g.players,
+		Turn:    g.players[g.turn],
+		Phase:   "finished",
+		Piles:   map[string]client.Pile{"table": {Cards: intoCodes(g.table)}},
+	}
+	for _, p := range g.players {
+		if len(g.hands[p]) > 0 {
+			v.Phase = "playing"
+		}
+		if p == player {
+			v.Piles[handOf(p)] = client.Pile{Cards: intoCodes(g.hands[p])}
+			continue
+		}
+		count := len(g.hands[p])
+		v.Piles[handOf(p)] = client.Pile{Count: &count}
+	}
+
+	if v.IsFinished() {
+		v.Scores = map[string]int{}
+		for _, p := range g.players {
+			v.Scores[p] = 1
+		}
+	} else if v.Turn == player {
+		for _, code := range g.hands[player] {
+			v.Legal = append(v.Legal, client.Action{Type: "play", Cards: []string{code}})
+		}
+	}
+	return v
+}
+
+func intoCodes(codes []string) []client.Card {
+	cards := []client.Card{}
+	for _, code := range codes {
+		cards = append(cards, client.Card{Code: code})
+	}
+	return cards
+}
+
+func (g *fakeGame) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	player := r.URL.Query().Get("player")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/games/laydown":
+		g.players = strings.Split(r.URL.Query().Get("players"), ",")
+		deal := [][]string{{"AS", "KD"}, {"2C", "3C"}}
+		for i, p := range g.players {
+			g.hands[p] = deal[i%len(deal)]
+		}
+		if !slices.Contains(g.players, player) {
+			player = ""
+		}
+	case r.Method == http.MethodPost:
+		var action client.Action
+		json.NewDecoder(r.Body).Decode(&action)
+		index := slices.Index(g.hands[player], action.Cards[0])
+		if player != g.players[g.turn] || index < 0 {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"status":409,"code":"conflict","detail":"Not your turn"}`))
+			return
+		}
+		g.hands[player] = slices.Delete(slices.Clone(g.hands[player]), index, index+1)
+		g.table = append(g.table, action.Cards[0])
+		g.moves = append(g.moves, player+" "+action.Cards[0])
+		g.turn = (g.turn + 1) % len(g.players)
+	}
+	json.NewEncoder(w).Encode(g.view(player))
+}
+
+func TestPlay(t *testing.T) {
+	t.Run("plays against the engine until the game is over", func(t *testing.T) {
+		game := newFakeGame()
+		server := httptest.NewServer(game)
+		defer server.Close()
+
+		// alice plays her second card, then her first
+		stdout, stderr, code := play(t, "l\r\r", "play", "laydown", "--players", "alice,bob", "--server", server.URL)
+		if code != 0 {
+			t.Fatalf("Expected to play the game, found %v: %v", code, stderr)
+		}
+
+		if len(game.moves) != 4 || game.moves[0] != "alice KD" || game.moves[2] != "alice AS" {
+			t.Errorf("Expected alice and the engine to take turns, found %v", game.moves)
+		}
+		if !strings.Contains(stdout, "scores: alice 1 · bob 1") {
+			t.Errorf("Expected the final scores on screen, found\n%v", stdout)
+		}
+	})
+
+	t.Run("plays the seat of --player", func(t *testing.T) {
+		game := newFakeGame()
+		server := httptest.NewServer(game)
+		defer server.Close()
+
+		_, stderr, code := play(t, "\r\r", "play", "laydown", "--players", "alice,bob", "--player", "bob", "--server", server.URL)
+		if code != 0 || len(game.moves) != 4 || game.moves[1] != "bob 2C" {
+			t.Errorf("Expected bob at the keyboard after the engine's alice, found %v (%v)", game.moves, stderr)
+		}
+	})
+
+	t.Run("shows errors and lets the player quit", func(t *testing.T) {
+		game := newFakeGame()
+		game.players = []string{"alice", "bob"}
+		game.hands = map[string][]string{"alice": {"AS"}, "bob": {"2C"}}
+		game.turn = 1
+		server := httptest.NewServer(game)
+		defer server.Close()
+
+		// nobody plays bob, so alice waits and has nothing to play
+		stdout, _, code := play(t, "\rq\r", "play", "laydown", game.id.String(), "--player", "alice", "--server", server.URL)
+		if code != 0 || len(game.moves) != 0 {
+			t.Errorf("Expected alice to quit without a move, found %v %v", code, game.moves)
+		}
+		if !strings.Contains(stdout, "It's bob's turn") {
+			t.Errorf("Expected to be told to wait for bob, found\n%v", stdout)
+		}
+	})
+
+	t.Run("refuses bots on an authenticated server", func(t *testing.T) {
+		game := newFakeGame()
+		server := httptest.NewServer(game)
+		defer server.Close()
+
+		_, stderr, code := play(t, "", "play", "laydown", "--players", "alice,bob", "--bots", "bob", "--api-key", "alices-key", "--server", server.URL)
+		if code != 1 || !strings.Contains(stderr, "cannot play bob on an authenticated server") {
+			t.Errorf("Expected the engine to refuse bob's seat, found %v: %v", code, stderr)
+		}
+		if game.players != nil {
+			t.Errorf("Expected no game to be created, found %v", game.players)
+		}
+	})
+
+	t.Run("leaves the other seats to their players when authenticated", func(t *testing.T) {
+		game := newFakeGame()
+		server := httptest.NewServer(game)
+		defer server.Close()
+
+		_, stderr, code := play(t, "\r", "play", "laydown", "--players", "alice,bob", "--player", "alice", "--api-key", "alices-key", "--server", server.URL)
+		if code != 0 || !slices.Equal(game.moves, []string{"alice AS"}) {
+			t.Errorf("Expected bob's seat to be left to bob, found %v (%v)", game.moves, stderr)
+		}
+	})
+
+	t.Run("needs a server", func(t *testing.T) {
+		if _, _, code := play(t, "", "play", "laydown", "--offline"); code != 1 {
+			t.Errorf("Expected playing offline to fail, found %v", code)
+		}
+	})
+}
+
+func TestReadKeys(t *testing.T) {
+	// typed at once, then nothing more for as long as the test runs
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	go io.WriteString(writer, "ll")
+
+	done := make(chan struct{})
+	keys := readKeys(reader, done)
+	if k := <-keys; k.key != keyRight {
+		t.Fatalf("Expected the first key, found %v", k)
+	}
+
+	// the second key may still be on its way, but nothing is read after it
+	close(done)
+	timeout := time.After(time.Second)
+	for received := 0; received < 2; received += 1 {
+		select {
+		case _, ok := <-keys:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Expected the keys to be closed once done")
+		}
+	}
+	t.Errorf("Expected the keys to stop once done")
+}
+
+// runs cards with keys as what's typed at the keyboard
+func play(t *testing.T, keys string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	code := run(context.Background(), args, strings.NewReader(keys), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"example.com/client"
+	"example.com/deck"
+	"strings"
+)
+
+// cards as package deck renders them. Codes that don't parse (a newer server
+// may know cards we don't) are printed as they are
+func renderCards(cards []client.Card, color bool) string {
+	faces := []string{}
+	for _, card := range cards {
+		parsed, err := deck.ParseCard(card.Code)
+		if err != nil {
+			faces = append(faces, card.Code)
+			continue
+		}
+		faces = append(faces, deck.RenderCards([]deck.Card{parsed}, color))
+	}
+	return strings.Join(faces, " ")
+}
+
+func renderBacks(count int) string {
+	return deck.RenderBacks(count)
+}
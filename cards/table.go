@@ -0,0 +1,213 @@
+package main
+
+import (
+	"example.com/client"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// what a key press means at the table
+type key int
+
+const (
+	keyLeft key = iota
+	keyRight
+	keyPlay
+	keyPick
+	keyRefresh
+	keyQuit
+)
+
+// digits pick the action of that number right away
+type keypress struct {
+	key   key
+	index int
+}
+
+// a chunk read off the terminal may hold several keys, arrows come as escape
+// sequences
+func parseKeys(data []byte) []keypress {
+	keys := []keypress{}
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; {
+		case b == 0x1b && i+2 < len(data) && data[i+1] == '[':
+			switch data[i+2] {
+			case 'D':
+				keys = append(keys, keypress{key: keyLeft})
+			case 'C':
+				keys = append(keys, keypress{key: keyRight})
+			}
+			i += 2
+		case b == 'h' || b == 'a':
+			keys = append(keys, keypress{key: keyLeft})
+		case b == 'l' || b == 'd':
+			keys = append(keys, keypress{key: keyRight})
+		case b == '\r' || b == '\n' || b == ' ':
+			keys = append(keys, keypress{key: keyPlay})
+		case b == 'r':
+			keys = append(keys, keypress{key: keyRefresh})
+		case b == 'q' || b == 3 || b == 4:
+			keys = append(keys, keypress{key: keyQuit})
+		case b >= '1' && b <= '9':
+			keys = append(keys, keypress{keyPick, int(b - '1')})
+		}
+	}
+	return keys
+}
+
+// the game as the player at the keyboard sees it, with the legal action
+// they are about to take
+type table struct {
+	view    client.View
+	cursor  int
+	message string
+}
+
+// keeps the cursor on a legal action when the view changes under it
+func (t *table) update(view client.View) {
+	t.view = view
+	t.cursor = min(t.cursor, max(len(view.Legal)-1, 0))
+}
+
+func (t *table) move(delta int) {
+	if len(t.view.Legal) == 0 {
+		return
+	}
+	t.cursor = (t.cursor + delta + len(t.view.Legal)) % len(t.view.Legal)
+}
+
+func (t *table) pick(index int) bool {
+	if index < 0 || index >= len(t.view.Legal) {
+		return false
+	}
+	t.cursor = index
+	return true
+}
+
+func (t *table) selected() (client.Action, bool) {
+	if len(t.view.Legal) == 0 {
+		return client.Action{}, false
+	}
+	return t.view.Legal[t.cursor], true
+}
+
+// opponents first, then whatever lies on the table and the player's own
+// hand last, right above the action they are about to take
+func (t *table) render(color bool) string {
+	v := t.view
+	b := &strings.Builder{}
+
+	status := v.Phase
+	if !v.IsFinished() {
+		status = fmt.Sprintf("%v's turn", v.Turn)
+		if v.Turn == v.Player {
+			status = "your turn"
+		}
+	}
+	fmt.Fprintf(b, "%v %v · %v · %d in the stock\n\n", v.Type, shortID(v), status, v.Remaining)
+
+	hands := map[string]bool{}
+	rows := [][2]string{}
+	for _, player := range v.Players {
+		hands[handOf(player)] = true
+		if player == v.Player {
+			continue
+		}
+		pile, ok := v.Piles[handOf(player)]
+		if !ok {
+			continue
+		}
+		rows = append(rows, [2]string{player, renderPile(pile, color, nil)})
+	}
+
+	names := []string{}
+	for name := range v.Piles {
+		if !hands[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows = append(rows, [2]string{name, renderPile(v.Piles[name], color, nil)})
+	}
+
+	if pile, ok := v.Piles[handOf(v.Player)]; ok {
+		action, _ := t.selected()
+		rows = append(rows, [2]string{"you", renderPile(pile, color, action.Cards)})
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row[0]))
+	}
+	for _, row := range rows {
+		fmt.Fprintf(b, "%-*v  %v\n", width, row[0], row[1])
+	}
+	fmt.Fprintln(b)
+
+	if v.IsFinished() {
+		fmt.Fprintf(b, "scores: %v\n", renderScores(v))
+		fmt.Fprintln(b, "q quits")
+	} else if action, ok := t.selected(); ok {
+		fmt.Fprintf(b, "> %v (%d/%d)\n", renderAction(action, color), t.cursor+1, len(v.Legal))
+		fmt.Fprintln(b, "←/→ chooses · enter plays · q quits")
+	} else {
+		fmt.Fprintf(b, "waiting for %v · q quits\n", v.Turn)
+	}
+	if t.message != "" {
+		fmt.Fprintln(b, t.message)
+	}
+	return b.String()
+}
+
+func handOf(player string) string {
+	return "hand:" + player
+}
+
+func shortID(v client.View) string {
+	return strings.SplitN(v.ID.String(), "-", 2)[0]
+}
+
+// the cards of the selected action stand out in brackets
+func renderPile(pile client.Pile, color bool, selected []string) string {
+	if len(pile.Cards) == 0 {
+		if pile.Count != nil && *pile.Count > 0 {
+			return renderBacks(*pile.Count)
+		}
+		return "-"
+	}
+
+	chosen := map[string]bool{}
+	for _, code := range selected {
+		chosen[code] = true
+	}
+	faces := []string{}
+	for _, card := range pile.Cards {
+		face := renderCards([]client.Card{card}, color)
+		if chosen[card.Code] {
+			face = "[" + face + "]"
+		}
+		faces = append(faces, face)
+	}
+	return strings.Join(faces, " ")
+}
+
+func renderAction(action client.Action, color bool) string {
+	if len(action.Cards) == 0 {
+		return action.Type
+	}
+	cards := []client.Card{}
+	for _, code := range action.Cards {
+		cards = append(cards, client.Card{Code: code})
+	}
+	return action.Type + " " + renderCards(cards, color)
+}
+
+func renderScores(v client.View) string {
+	scores := []string{}
+	for _, player := range v.Players {
+		scores = append(scores, fmt.Sprintf("%v %d", player, v.Scores[player]))
+	}
+	return strings.Join(scores, " · ")
+}
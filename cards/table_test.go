@@ -0,0 +1,97 @@
+package main
+
+import (
+	"example.com/client"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("\x1b[C\x1b[Dl h\r3q\x03x"))
+	expected := []keypress{
+		{key: keyRight}, {key: keyLeft}, {key: keyRight}, {key: keyPlay},
+		{key: keyLeft}, {key: keyPlay}, {keyPick, 2}, {key: keyQuit}, {key: keyQuit},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, found %v", expected, keys)
+	}
+}
+
+func TestTable(t *testing.T) {
+	three := 3
+	view := client.View{
+		Type:    "highcard",
+		Player:  "alice",
+		Players: []string{"alice", "bob"},
+		Turn:    "alice",
+		Phase:   "playing",
+		Piles: map[string]client.Pile{
+			"hand:alice": {Cards: []client.Card{{Code: "AS"}, {Code: "10H"}}},
+			"hand:bob":   {Count: &three},
+			"table":      {Cards: []client.Card{{Code: "KD"}}},
+		},
+		Remaining: 42,
+		Legal: []client.Action{
+			{Type: "play", Cards: []string{"AS"}},
+			{Type: "play", Cards: []string{"10H"}},
+		},
+	}
+	tb := &table{}
+	tb.update(view)
+
+	t.Run("moves around the legal actions", func(t *testing.T) {
+		tb.move(-1)
+		if action, _ := tb.selected(); action.Cards[0] != "10H" {
+			t.Errorf("Expected to wrap around to 10H, found %v", action)
+		}
+		tb.move(1)
+		if action, _ := tb.selected(); action.Cards[0] != "AS" {
+			t.Errorf("Expected to move back to AS, found %v", action)
+		}
+	})
+
+	t.Run("picks actions by number", func(t *testing.T) {
+		if tb.pick(2) {
+			t.Errorf("Expected there to be no third action")
+		}
+		if !tb.pick(1) || tb.cursor != 1 {
+			t.Errorf("Expected to pick the second action, found %v", tb.cursor)
+		}
+	})
+
+	t.Run("renders opponents first and the hand last", func(t *testing.T) {
+		screen := tb.render(false)
+		expected := []string{
+			"your turn · 42 in the stock",
+			"bob    🂠 🂠 🂠\ntable  K♦\nyou    A♠ [10♥]\n",
+			"> play 10♥ (2/2)",
+		}
+		for _, e := range expected {
+			if !strings.Contains(screen, e) {
+				t.Errorf("Expected %q in\n%v", e, screen)
+			}
+		}
+	})
+
+	t.Run("keeps the cursor on a legal action", func(t *testing.T) {
+		waiting := view
+		waiting.Turn = "bob"
+		waiting.Legal = nil
+		tb.update(waiting)
+		if _, ok := tb.selected(); ok || !strings.Contains(tb.render(false), "waiting for bob") {
+			t.Errorf("Expected to wait for bob, found cursor at %v", tb.cursor)
+		}
+	})
+
+	t.Run("renders scores once finished", func(t *testing.T) {
+		finished := view
+		finished.Phase = "finished"
+		finished.Legal = nil
+		finished.Scores = map[string]int{"alice": 3, "bob": 2}
+		tb.update(finished)
+		if screen := tb.render(false); !strings.Contains(screen, "scores: alice 3 · bob 2") {
+			t.Errorf("Expected the scores, found\n%v", screen)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+)
+
+// puts the terminal in raw mode so that keys are read as they are pressed.
+// Input that isn't a terminal (a pipe, a test) is read as it comes. The
+// returned func puts things back the way they were
+func rawMode(in io.Reader) (func(), error) {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return func() {}, nil
+	}
+
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return func() {}, err
+	}
+	return func() { term.Restore(int(f.Fd()), state) }, nil
+}
+
+// key presses as they are read, closed once in is. Reading stops once done
+// is closed, as soon as a read that's under way returns
+func readKeys(in io.Reader, done <-chan struct{}) <-chan keypress {
+	keys := make(chan keypress)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			n, err := in.Read(buf)
+			for _, k := range parseKeys(buf[:n]) {
+				select {
+				case keys <- k:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+// a terminal in raw mode no longer turns \n into \r\n
+func (c *cli) draw(screen string) {
+	if c.interactive {
+		screen = clearScreen + strings.ReplaceAll(screen, "\n", "\r\n")
+	} else {
+		screen += "\n"
+	}
+	io.WriteString(c.out, screen)
+}
@@ -0,0 +1,254 @@
+// A typed client for the card games engine HTTP API (see
+// server/openapi.json)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	apiKey     string
+	player     string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// authenticates with a JWT signed by the server's CARDS_AUTH_SECRET
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// authenticates with one of the server's CARDS_API_KEYS
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// says who is asking when the server has authentication turned off
+func WithPlayer(player string) Option {
+	return func(c *Client) { c.player = player }
+}
+
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// the same client asking as someone else, i.e. to see another player's
+// view of a game when the server has authentication turned off
+func (c *Client) As(player string) *Client {
+	other := *c
+	other.player = player
+	return &other
+}
+
+type CreateOptions struct {
+	// card codes as in AS or 10H, the full deck when empty
+	Cards      []string
+	Shuffled   bool
+	Visibility Visibility
+	Rules      string
+	TTL        time.Duration
+	// retries with the same key get the original deck back
+	IdempotencyKey string
+}
+
+func (c *Client) CreateDeck(ctx context.Context, options CreateOptions) (CreatedDeck, error) {
+	query := url.Values{}
+	if len(options.Cards) > 0 {
+		query.Set("cards", strings.Join(options.Cards, ","))
+	}
+	if options.Shuffled {
+		query.Set("shuffled", "true")
+	}
+	if options.Visibility != "" {
+		query.Set("visibility", string(options.Visibility))
+	}
+	if options.Rules != "" {
+		query.Set("rules", options.Rules)
+	}
+	if options.TTL > 0 {
+		query.Set("ttl", options.TTL.String())
+	}
+
+	var created CreatedDeck
+	headers := conditions{idempotencyKey: options.IdempotencyKey}
+	err := c.do(ctx, http.MethodPost, "/v1/decks", query, headers, nil, &created)
+	return created, err
+}
+
+func (c *Client) OpenDeck(ctx context.Context, id uuid.UUID) (Deck, error) {
+	var deck Deck
+	err := c.do(ctx, http.MethodGet, "/v1/decks/"+id.String(), url.Values{}, conditions{}, nil, &deck)
+	return deck, err
+}
+
+type DrawOptions struct {
+	// 1 when left at 0
+	Count int
+	// keeps the drawn cards in a pile owned by the player
+	Pile           string
+	PileVisibility Visibility
+	// settles for whatever is left instead of failing with ErrNotEnoughCards
+	AllowPartial bool
+	// only draws if the deck is still at this version, see Deck.Version
+	IfVersion      *int
+	IdempotencyKey string
+}
+
+func (c *Client) Draw(ctx context.Context, id uuid.UUID, options DrawOptions) ([]Card, error) {
+	query := url.Values{}
+	if options.Count != 0 {
+		query.Set("count", strconv.Itoa(options.Count))
+	}
+	if options.Pile != "" {
+		query.Set("pile", options.Pile)
+	}
+	if options.PileVisibility != "" {
+		query.Set("pile_visibility", string(options.PileVisibility))
+	}
+	if options.AllowPartial {
+		query.Set("allow_partial", "true")
+	}
+
+	var cards []Card
+	headers := conditions{options.IfVersion, options.IdempotencyKey}
+	err := c.do(ctx, http.MethodPost, "/v1/decks/"+id.String()+"/draws", query, headers, nil, &cards)
+	return cards, err
+}
+
+type DealOptions struct {
+	Players []string
+	// cards per player, 1 when left at 0
+	Count int
+	// who sees the piles dealt into, only their owners when empty
+	PileVisibility Visibility
+	// only deals if the deck is still at this version, see Deck.Version
+	IfVersion      *int
+	IdempotencyKey string
+}
+
+// deals to every player in turn into a pile named after and owned by them,
+// and answers with the deck as the dealer sees it
+func (c *Client) Deal(ctx context.Context, id uuid.UUID, options DealOptions) (Deck, error) {
+	query := url.Values{"players": {strings.Join(options.Players, ",")}}
+	if options.Count != 0 {
+		query.Set("count", strconv.Itoa(options.Count))
+	}
+	if options.PileVisibility != "" {
+		query.Set("pile_visibility", string(options.PileVisibility))
+	}
+
+	var deck Deck
+	headers := conditions{options.IfVersion, options.IdempotencyKey}
+	err := c.do(ctx, http.MethodPost, "/v1/decks/"+id.String()+"/deals", query, headers, nil, &deck)
+	return deck, err
+}
+
+func (c *Client) Shuffle(ctx context.Context, id uuid.UUID) (CreatedDeck, error) {
+	var shuffled CreatedDeck
+	err := c.do(ctx, http.MethodPost, "/v1/decks/"+id.String()+"/shuffles", url.Values{}, conditions{}, nil, &shuffled)
+	return shuffled, err
+}
+
+func (c *Client) Share(ctx context.Context, id uuid.UUID, players ...string) (Deck, error) {
+	query := url.Values{"with": {strings.Join(players, ",")}}
+	var deck Deck
+	err := c.do(ctx, http.MethodPost, "/v1/decks/"+id.String()+"/shares", query, conditions{}, nil, &deck)
+	return deck, err
+}
+
+// the snapshot is kept as is so that it can be saved and imported again
+// without losing anything
+func (c *Client) Export(ctx context.Context, id uuid.UUID) (json.RawMessage, error) {
+	var snapshot json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/v1/decks/"+id.String()+"/export", url.Values{}, conditions{}, nil, &snapshot)
+	return snapshot, err
+}
+
+// the optional headers of a request
+type conditions struct {
+	ifVersion      *int
+	idempotencyKey string
+}
+
+// body, when not nil, is sent as JSON
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, headers conditions, body any, out any) error {
+	if c.player != "" {
+		query.Set("player", c.player)
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, target, payload)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", "application/json")
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.apiKey != "" {
+		r.Header.Set("X-API-Key", c.apiKey)
+	}
+	if headers.ifVersion != nil {
+		r.Header.Set("If-Match", fmt.Sprintf(`"%d"`, *headers.ifVersion))
+	}
+	if headers.idempotencyKey != "" {
+		r.Header.Set("Idempotency-Key", headers.idempotencyKey)
+	}
+
+	res, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	answer, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 400 {
+		return decodeError(res, answer)
+	}
+	if err := json.Unmarshal(answer, out); err != nil {
+		return fmt.Errorf("Failed to decode %v %v: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequests(t *testing.T) {
+	var last *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/decks" {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"deck_id":"67e55044-10b1-426f-9247-bb680e5fe0c8","shuffled":true,"remaining":2}`))
+			return
+		}
+		w.Write([]byte(`[{"value":"ACE","suit":"SPADES","code":"AS"}]`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", WithAPIKey("alices-key"), WithPlayer("alice"))
+
+	t.Run("creates deck", func(t *testing.T) {
+		options := CreateOptions{Cards: []string{"AS", "KD"}, Shuffled: true, Visibility: FaceDown}
+		created, err := c.CreateDeck(context.Background(), options)
+		if err != nil || created.Remaining != 2 || !created.Shuffled {
+			t.Fatalf("Expected the created deck to be decoded, found %v (%v)", created, err)
+		}
+
+		query := last.URL.Query()
+		if last.Method != http.MethodPost || query.Get("cards") != "AS,KD" || query.Get("visibility") != "facedown" {
+			t.Errorf("Expected options to be sent as query params, found %v %v", last.Method, last.URL)
+		}
+		if last.Header.Get("X-API-Key") != "alices-key" || query.Get("player") != "alice" {
+			t.Errorf("Expected the api key and player to be sent, found %v", last.URL)
+		}
+	})
+
+	t.Run("draws", func(t *testing.T) {
+		version := 3
+		options := DrawOptions{Count: 1, Pile: "hand", IfVersion: &version, IdempotencyKey: "draw-1"}
+		cards, err := c.Draw(context.Background(), uuid.New(), options)
+		if err != nil || len(cards) != 1 || cards[0].Code != "AS" {
+			t.Fatalf("Expected the drawn card to be decoded, found %v (%v)", cards, err)
+		}
+
+		if last.Header.Get("If-Match") != `"3"` || last.Header.Get("Idempotency-Key") != "draw-1" {
+			t.Errorf("Expected If-Match and Idempotency-Key to be sent, found %v", last.Header)
+		}
+		if last.URL.Query().Get("count") != "1" || last.URL.Query().Get("pile") != "hand" {
+			t.Errorf("Expected count and pile to be sent, found %v", last.URL)
+		}
+	})
+
+	t.Run("sends actions as JSON", func(t *testing.T) {
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			last = r
+			body, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`{"game_id":"67e55044-10b1-426f-9247-bb680e5fe0c8","turn":"bob","phase":"playing"}`))
+		}))
+		defer server.Close()
+
+		id := uuid.New()
+		view, err := New(server.URL).As("alice").Act(context.Background(), "highcard", id, Action{"play", []string{"KH"}})
+		if err != nil || view.Turn != "bob" {
+			t.Fatalf("Expected the view to be decoded, found %v (%v)", view, err)
+		}
+		if string(body) != `{"type":"play","cards":["KH"]}` || last.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the action as JSON, found %s", body)
+		}
+		if last.URL.Path != "/v1/games/highcard/"+id.String()+"/actions" || last.URL.Query().Get("player") != "alice" {
+			t.Errorf("Expected alice to act on the game, found %v", last.URL)
+		}
+	})
+
+	t.Run("exports snapshot as is", func(t *testing.T) {
+		id := uuid.New()
+		snapshot, err := c.Export(context.Background(), id)
+		if err != nil || string(snapshot) != `[{"value":"ACE","suit":"SPADES","code":"AS"}]` {
+			t.Fatalf("Expected the body to be kept as is, found %s (%v)", snapshot, err)
+		}
+		if last.Method != http.MethodGet || last.URL.Path != "/v1/decks/"+id.String()+"/export" {
+			t.Errorf("Expected the snapshot to be fetched, found %v %v", last.Method, last.URL)
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/decks" && r.URL.Query().Has("cards") {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"/problems/deck-too-large","title":"Deck too large","status":400,"detail":"Deck too large: a deck may have at most 520 cards, found 521","code":"deck_too_large"}`))
+			return
+		}
+		if r.URL.Path == "/v1/decks" {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"type":"/problems/rate-limited","title":"Too many requests","status":429,"detail":"Too many requests: Open may be called 1 times per second, try again in 1s","code":"rate_limited"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"type":"/problems/not-enough-cards","title":"Not enough cards","status":409,"detail":"Cannot draw 5 cards, only 3 left","code":"not_enough_cards","remaining":3}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+
+	t.Run("decodes problem details", func(t *testing.T) {
+		_, err := c.Draw(context.Background(), uuid.New(), DrawOptions{Count: 5})
+		if !errors.Is(err, ErrNotEnoughCards) || errors.Is(err, ErrDeckNotFound) {
+			t.Fatalf("Expected ErrNotEnoughCards, found %v", err)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) || e.Status != http.StatusConflict || e.Remaining == nil || *e.Remaining != 3 {
+			t.Errorf("Expected status and remaining cards to be decoded, found %v", e)
+		}
+	})
+
+	t.Run("tells limits apart", func(t *testing.T) {
+		_, err := c.OpenDeck(context.Background(), uuid.New())
+		if !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTooManyRequests) {
+			t.Errorf("Expected ErrRateLimited, found %v", err)
+		}
+
+		_, err = c.CreateDeck(context.Background(), CreateOptions{Cards: []string{"AS"}})
+		if !errors.Is(err, ErrDeckTooLarge) {
+			t.Errorf("Expected ErrDeckTooLarge, found %v", err)
+		}
+	})
+
+	t.Run("derives code of other errors from status", func(t *testing.T) {
+		_, err := c.CreateDeck(context.Background(), CreateOptions{})
+		var e *Error
+		if !errors.As(err, &e) || e.Code != "bad_gateway" || e.Detail != "bad gateway" {
+			t.Errorf("Expected a bad_gateway error, found %v", err)
+		}
+	})
+}
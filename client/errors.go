@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Error is the problem details the server answers errors with. Compare it
+// to the sentinels below with errors.Is, which only looks at the code
+type Error struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	Param     string `json:"param,omitempty"`
+	Input     string `json:"input,omitempty"`
+	Remaining *int   `json:"remaining,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.Code == e.Code
+}
+
+// one for every code the server may answer with
+var (
+	ErrInvalidGuid          = &Error{Code: "invalid_guid"}
+	ErrInvalidCard          = &Error{Code: "invalid_card"}
+	ErrInvalidCount         = &Error{Code: "invalid_count"}
+	ErrInvalidParameter     = &Error{Code: "invalid_parameter"}
+	ErrNotEnoughCards       = &Error{Code: "not_enough_cards"}
+	ErrForeignPile          = &Error{Code: "foreign_pile"}
+	ErrDeckNotFound         = &Error{Code: "deck_not_found"}
+	ErrGameNotFound         = &Error{Code: "game_not_found"}
+	ErrDeckExpired          = &Error{Code: "deck_expired"}
+	ErrGameExpired          = &Error{Code: "game_expired"}
+	ErrEventsGone           = &Error{Code: "events_gone"}
+	ErrIdempotencyKeyReused = &Error{Code: "idempotency_key_reused"}
+	ErrVersionMismatch      = &Error{Code: "version_mismatch"}
+	ErrUnauthorized         = &Error{Code: "unauthorized"}
+	ErrForbidden            = &Error{Code: "forbidden"}
+	ErrTooManyRequests      = &Error{Code: "too_many_requests"}
+	ErrRateLimited          = &Error{Code: "rate_limited"}
+	ErrDeckTooLarge         = &Error{Code: "deck_too_large"}
+	ErrTooManyDecks         = &Error{Code: "too_many_decks"}
+)
+
+// responses that aren't problem details (i.e. from a proxy) get a code
+// derived from their status like the server does
+func decodeError(res *http.Response, body []byte) error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err == nil && e.Code != "" {
+		return e
+	}
+
+	title := http.StatusText(res.StatusCode)
+	return &Error{
+		Title:  title,
+		Status: res.StatusCode,
+		Detail: strings.TrimSpace(string(body)),
+		Code:   strings.ToLower(strings.ReplaceAll(title, " ", "_")),
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// what a player may do next, as in {"type":"play","cards":["KH"]}
+type Action struct {
+	Type  string   `json:"type"`
+	Cards []string `json:"cards,omitempty"`
+}
+
+// a game as seen by a single player. Hands are piles named hand:<player>
+// and opponents' hands only come with a count
+type View struct {
+	ID        uuid.UUID       `json:"game_id"`
+	Type      string          `json:"type"`
+	Player    string          `json:"player"`
+	Players   []string        `json:"players"`
+	Turn      string          `json:"turn"`
+	Phase     string          `json:"phase"`
+	Piles     map[string]Pile `json:"piles"`
+	Remaining int             `json:"remaining"`
+	Legal     []Action        `json:"legal_actions"`
+	// only set once the game is over
+	Scores map[string]int `json:"scores,omitempty"`
+	Extra  any            `json:"extra,omitempty"`
+}
+
+func (v *View) IsFinished() bool {
+	return v.Phase == "finished"
+}
+
+// answers with the view of the client's player, or of a spectator when they
+// have no seat at the game
+func (c *Client) CreateGame(ctx context.Context, gameType string, players ...string) (View, error) {
+	query := url.Values{"players": {strings.Join(players, ",")}}
+	var view View
+	err := c.do(ctx, http.MethodPost, "/v1/games/"+gameType, query, conditions{}, nil, &view)
+	return view, err
+}
+
+func (c *Client) ViewGame(ctx context.Context, gameType string, id uuid.UUID) (View, error) {
+	var view View
+	err := c.do(ctx, http.MethodGet, "/v1/games/"+gameType+"/"+id.String(), url.Values{}, conditions{}, nil, &view)
+	return view, err
+}
+
+// answers with the view of the game right after the action
+func (c *Client) Act(ctx context.Context, gameType string, id uuid.UUID, action Action) (View, error) {
+	var view View
+	path := "/v1/games/" + gameType + "/" + id.String() + "/actions"
+	err := c.do(ctx, http.MethodPost, path, url.Values{}, conditions{}, action, &view)
+	return view, err
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"github.com/google/uuid"
+)
+
+type Visibility string
+
+const (
+	Public    Visibility = "public"
+	OwnerOnly Visibility = "owner"
+	FaceDown  Visibility = "facedown"
+	Hidden    Visibility = "hidden"
+)
+
+type CreatedDeck struct {
+	ID        uuid.UUID `json:"deck_id"`
+	Shuffled  bool      `json:"shuffled"`
+	Remaining int       `json:"remaining"`
+}
+
+// only holds what the player is allowed to see, so Cards is empty when the
+// deck is face down
+type Deck struct {
+	ID         uuid.UUID       `json:"deck_id"`
+	Shuffled   bool            `json:"shuffled"`
+	Remaining  int             `json:"remaining"`
+	Owner      string          `json:"owner,omitempty"`
+	SharedWith []string        `json:"shared_with,omitempty"`
+	Visibility Visibility      `json:"visibility"`
+	Cards      []Card          `json:"cards,omitempty"`
+	Piles      map[string]Pile `json:"piles,omitempty"`
+	// goes up with every change, see DrawOptions.IfVersion
+	Version int `json:"version"`
+}
+
+// Count is only set when the cards themselves can't be seen
+type Pile struct {
+	Owner      string     `json:"owner,omitempty"`
+	Visibility Visibility `json:"visibility"`
+	Count      *int       `json:"count,omitempty"`
+	Cards      []Card     `json:"cards,omitempty"`
+}
+
+type Card struct {
+	Value string `json:"value"`
+	Suit  string `json:"suit"`
+	Code  string `json:"code"`
+}
@@ -10,14 +10,44 @@ import (
 )
 
 type Deck struct {
-	Cards []Card
-	Guid  uuid.UUID
+	Cards      []Card
+	Guid       uuid.UUID
+	Owner      string
+	Visibility Visibility
+	Piles      map[string]Pile
+	SharedWith []string
+	History    []Event
+	Rules      string
+	// goes up with every change to the deck, undoing included, so that
+	// clients can tell whether what they saw is still current
+	Version int
 }
 
 func (d *Deck) RemainingCardCount() int {
 	return len(d.Cards)
 }
 
+// decks without an owner can be used by anyone
+func (d *Deck) IsAccessibleBy(player string) bool {
+	if d.Owner == "" || d.Owner == player {
+		return true
+	}
+	for _, shared := range d.SharedWith {
+		if shared == player {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Deck) Share(players ...string) {
+	for _, player := range players {
+		if !d.IsAccessibleBy(player) {
+			d.SharedWith = append(d.SharedWith, player)
+		}
+	}
+}
+
 type Card struct {
 	Rank Rank
 	Suit Suit
@@ -25,9 +55,13 @@ type Card struct {
 
 func (c *Card) Code() string {
 	// using the ASCII subset of UTF-8 so this is ok
-	rank := c.Rank.String()[0]
-	suit := c.Suit.String()[0]
-	return fmt.Sprintf("%s%s", string(rank), string(suit))
+	rank := c.Rank.String()[:1]
+	if c.Rank == V10 {
+		// the only rank whose code takes up two characters
+		rank = c.Rank.String()
+	}
+	suit := c.Suit.String()[:1]
+	return fmt.Sprintf("%s%s", rank, suit)
 }
 
 func newCard(rank Rank, suit Suit) Card {
@@ -36,11 +70,13 @@ func newCard(rank Rank, suit Suit) Card {
 
 func ParseCard(code string) (Card, error) {
 	n := len(code)
+	if n < 2 {
+		return defaultCard(), &InvalidCardError{code}
+	}
 	rank, rankErr := parseRank(code[:(n - 1)])
 	suit, suitErr := parseSuit(code[n-1:])
 	if suitErr != nil || rankErr != nil {
-		msg := fmt.Sprintf("Failed to parse %s into Card", code)
-		return defaultCard(), errors.New(msg)
+		return defaultCard(), &InvalidCardError{code}
 	}
 	return newCard(rank, suit), nil
 
@@ -80,11 +116,11 @@ func parseSuit(suit string) (Suit, error) {
 	case "S":
 		return Spades, nil
 	case "D":
-		return Spades, nil
+		return Diamonds, nil
 	case "C":
-		return Spades, nil
+		return Clubs, nil
 	case "H":
-		return Spades, nil
+		return Hearts, nil
 	default:
 		{
 			msg := fmt.Sprintf("Invalid suit code: %v", suit)
@@ -200,6 +236,13 @@ func NewDeck(cards []Card) Deck {
 	return Deck{
 		cards,
 		guid,
+		"",
+		Public,
+		map[string]Pile{},
+		[]string{},
+		[]Event{},
+		"",
+		0,
 	}
 }
 
@@ -273,3 +316,17 @@ func (d *Deck) Draw(count int) []Card {
 	d.Cards = remainingCards
 	return drawnCards
 }
+
+// unlike Draw, which settles for whatever is left, DrawExactly draws all of
+// count or nothing at all. Games can't deal half a hand
+func (d *Deck) DrawExactly(count int) ([]Card, error) {
+	if count < 0 {
+		return []Card{}, fmt.Errorf("%w: %d", ErrInvalidCount, count)
+	}
+
+	n := d.RemainingCardCount()
+	if count > n {
+		return []Card{}, &NotEnoughCardsError{count, n}
+	}
+	return d.Draw(count), nil
+}
@@ -1,6 +1,7 @@
 package deck
 
 import (
+	"errors"
 	"github.com/google/go-cmp/cmp"
 	"testing"
 )
@@ -52,6 +53,46 @@ func TestDrawMoreCardsThanAvailable(t *testing.T) {
 	}
 }
 
+func TestDrawExactly(t *testing.T) {
+	cards := []Card{
+		newCard(Ace, Spades),
+		newCard(King, Hearts),
+		newCard(Ace, Clubs),
+	}
+
+	t.Run("draws the requested cards", func(t *testing.T) {
+		deck := NewDeck(cards)
+		drawnCards, err := deck.DrawExactly(2)
+		if err != nil || len(drawnCards) != 2 || deck.RemainingCardCount() != 1 {
+			msg := "Expected 2 cards to be drawn and 1 to remain, found %v (%v)"
+			t.Errorf(msg, drawnCards, err)
+		}
+	})
+
+	t.Run("fails to draw more cards than available", func(t *testing.T) {
+		deck := NewDeck(cards)
+		_, err := deck.DrawExactly(5)
+
+		var notEnough *NotEnoughCardsError
+		if !errors.Is(err, ErrNotEnoughCards) || !errors.As(err, &notEnough) {
+			t.Fatalf("Expected ErrNotEnoughCards, found %v", err)
+		}
+		if notEnough.Requested != 5 || notEnough.Remaining != 3 {
+			t.Errorf("Expected 5 requested and 3 remaining, found %v", notEnough)
+		}
+		if deck.RemainingCardCount() != 3 {
+			t.Errorf("Expected a failed draw to leave the deck untouched")
+		}
+	})
+
+	t.Run("fails to draw a negative count", func(t *testing.T) {
+		deck := NewDeck(cards)
+		if _, err := deck.DrawExactly(-1); !errors.Is(err, ErrInvalidCount) {
+			t.Errorf("Expected ErrInvalidCount, found %v", err)
+		}
+	})
+}
+
 func TestIsShuffled(t *testing.T) {
 	unshuffledDeck := NewDefaultDeck()
 	if unshuffledDeck.IsShuffled() {
@@ -177,3 +218,52 @@ func TestDefaultDeck(t *testing.T) {
 		t.Errorf(msg, expectedCard, card)
 	}
 }
+
+func TestParseCardRoundTrip(t *testing.T) {
+	deck := NewDefaultDeck()
+	for _, card := range deck.Cards {
+		parsed, err := ParseCard(card.Code())
+		if err != nil {
+			t.Errorf("Expected %v to be parsed, instead got %v", card.Code(), err)
+		}
+		if parsed != card {
+			msg := "Expected code %v to be parsed into %v but found %v instead"
+			t.Errorf(msg, card.Code(), card, parsed)
+		}
+	}
+
+	if _, err := ParseCard("X"); err == nil {
+		t.Errorf("Expected err due to invalid card code")
+	}
+}
+
+func TestParseInvalidCard(t *testing.T) {
+	for _, code := range []string{"", "X", "1S", "AX", "11H"} {
+		_, err := ParseCard(code)
+		if !errors.Is(err, ErrInvalidCard) {
+			t.Errorf("Expected ErrInvalidCard for %q, found %v", code, err)
+		}
+
+		var cardErr *InvalidCardError
+		if !errors.As(err, &cardErr) || cardErr.Code != code {
+			t.Errorf("Expected the error to carry %q, found %v", code, err)
+		}
+	}
+}
+
+func TestIsAccessibleBy(t *testing.T) {
+	deck := NewDefaultDeck()
+	if !deck.IsAccessibleBy("bob") {
+		t.Errorf("Expected deck without owner to be accessible by anyone")
+	}
+
+	deck.Owner = "alice"
+	if deck.IsAccessibleBy("bob") {
+		t.Errorf("Expected alice's deck not to be accessible by bob")
+	}
+
+	deck.Share("bob", "bob")
+	if !deck.IsAccessibleBy("bob") || len(deck.SharedWith) != 1 {
+		t.Errorf("Expected alice's deck to be shared once with bob, found %v", deck.SharedWith)
+	}
+}
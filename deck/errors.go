@@ -0,0 +1,40 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+)
+
+// callers tell errors apart with errors.Is against these while the typed
+// errors below carry the input that caused them
+var (
+	ErrInvalidCard    = errors.New("Invalid card")
+	ErrNotEnoughCards = errors.New("Not enough cards")
+	ErrInvalidCount   = errors.New("Invalid count")
+	ErrForeignPile    = errors.New("Pile belongs to someone else")
+)
+
+type InvalidCardError struct {
+	Code string
+}
+
+func (e *InvalidCardError) Error() string {
+	return fmt.Sprintf("Failed to parse %s into Card", e.Code)
+}
+
+func (e *InvalidCardError) Is(target error) bool {
+	return target == ErrInvalidCard
+}
+
+type NotEnoughCardsError struct {
+	Requested int
+	Remaining int
+}
+
+func (e *NotEnoughCardsError) Error() string {
+	return fmt.Sprintf("Cannot draw %d cards, only %d left", e.Requested, e.Remaining)
+}
+
+func (e *NotEnoughCardsError) Is(target error) bool {
+	return target == ErrNotEnoughCards
+}
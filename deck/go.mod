@@ -1,3 +1,7 @@
 module example.com/deck
 
 go 1.21.6
+
+require github.com/google/uuid v1.6.0
+
+require github.com/google/go-cmp v0.6.0
@@ -0,0 +1,198 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+type EventType string
+
+const (
+	Created  EventType = "created"
+	Shuffled EventType = "shuffled"
+	Drawn    EventType = "drawn"
+	Shared   EventType = "shared"
+	Undone   EventType = "undone"
+	Redone   EventType = "redone"
+)
+
+// every mutation of a deck is recorded as an event holding enough to redo it
+// (i.e. the order the cards ended up in after a shuffle) so that the state of
+// a deck can always be rebuilt by replaying its history. Owner and
+// Visibility say who may see the cards of the event. Undoing and redoing are
+// events too, Targets being the events they take back or do again (newest
+// first for undo, oldest first for redo), so that history is never rewritten
+type Event struct {
+	Seq        int
+	Type       EventType
+	Actor      string
+	Time       time.Time
+	Cards      []string
+	Pile       string
+	Owner      string
+	Visibility Visibility
+	Players    []string
+	Targets    []int
+}
+
+func (d *Deck) record(e Event) Event {
+	e.Seq = len(d.History) + 1
+	e.Time = time.Now().UTC()
+	d.History = append(d.History, e)
+	d.Version += 1
+	return e
+}
+
+func codes(cards []Card) []string {
+	codes := []string{}
+	for _, card := range cards {
+		codes = append(codes, card.Code())
+	}
+	return codes
+}
+
+func (d *Deck) RecordCreate(actor string) Event {
+	e := Event{
+		Type:       Created,
+		Actor:      actor,
+		Cards:      codes(d.Cards),
+		Owner:      d.Owner,
+		Visibility: d.Visibility,
+	}
+	return d.record(e)
+}
+
+func (d *Deck) RecordShuffle(actor string) Event {
+	e := Event{
+		Type:       Shuffled,
+		Actor:      actor,
+		Cards:      codes(d.Cards),
+		Owner:      d.Owner,
+		Visibility: d.Visibility,
+	}
+	return d.record(e)
+}
+
+// cards drawn into a pile are as visible as the pile, otherwise only the one
+// who drew them got to see them
+func (d *Deck) RecordDraw(actor string, pile string, cards []Card) Event {
+	e := Event{
+		Type:       Drawn,
+		Actor:      actor,
+		Cards:      codes(cards),
+		Pile:       pile,
+		Owner:      actor,
+		Visibility: OwnerOnly,
+	}
+	if p, ok := d.Piles[pile]; ok {
+		e.Owner = p.Owner
+		e.Visibility = p.Visibility
+	}
+	return d.record(e)
+}
+
+func (d *Deck) RecordShare(actor string, players []string) Event {
+	e := Event{
+		Type:       Shared,
+		Actor:      actor,
+		Players:    players,
+		Visibility: Hidden,
+	}
+	return d.record(e)
+}
+
+func parseCodes(codes []string) ([]Card, error) {
+	cards := []Card{}
+	for _, code := range codes {
+		card, err := ParseCard(code)
+		if err != nil {
+			return []Card{}, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// applies the outcome of an event to the deck as if it had just happened
+func (d *Deck) Apply(e Event) error {
+	var err error
+	switch e.Type {
+	case Undone:
+		err = d.applyUndo(e)
+	case Redone:
+		err = d.applyRedo(e)
+	default:
+		err = d.applyOutcome(e)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.History = append(d.History, e)
+	d.Version += 1
+	return nil
+}
+
+func (d *Deck) applyOutcome(e Event) error {
+	cards, err := parseCodes(e.Cards)
+	if err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case Created:
+		d.Cards = cards
+		d.Owner = e.Owner
+		d.Visibility = e.Visibility
+	case Shuffled:
+		if len(cards) != d.RemainingCardCount() {
+			msg := fmt.Sprintf("Event %d shuffles %d cards but %d remain", e.Seq, len(cards), d.RemainingCardCount())
+			return errors.New(msg)
+		}
+		d.Cards = cards
+	case Drawn:
+		drawnCards := d.Draw(len(cards))
+		for i := range cards {
+			if i >= len(drawnCards) || drawnCards[i] != cards[i] {
+				msg := fmt.Sprintf("Event %d draws cards that were not on top of the deck", e.Seq)
+				return errors.New(msg)
+			}
+		}
+		if e.Pile != "" {
+			pile, ok := d.Piles[e.Pile]
+			if !ok {
+				pile = NewPile(e.Owner, e.Visibility)
+			}
+			pile.Cards = append(pile.Cards, drawnCards...)
+			d.Piles[e.Pile] = pile
+		}
+	case Shared:
+		d.Share(e.Players...)
+	default:
+		msg := fmt.Sprintf("Unknown event type %v", e.Type)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func Replay(guid uuid.UUID, events []Event) (Deck, error) {
+	d := NewEmptyDeck()
+	d.Guid = guid
+	for _, e := range events {
+		if err := d.Apply(e); err != nil {
+			return NewEmptyDeck(), err
+		}
+	}
+	return d, nil
+}
+
+// the deck as it was right after event seq
+func (d *Deck) AsOf(seq int) (Deck, error) {
+	if seq < 0 || seq > len(d.History) {
+		msg := fmt.Sprintf("Deck %v has no event %d", d.Guid, seq)
+		return NewEmptyDeck(), errors.New(msg)
+	}
+	return Replay(d.Guid, d.History[:seq])
+}
@@ -0,0 +1,73 @@
+package deck
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	original := NewDefaultDeck()
+	original.Owner = "alice"
+	original.RecordCreate("alice")
+	original.Shuffle()
+	original.RecordShuffle("alice")
+	original.RecordDraw("alice", "", original.Draw(3))
+	drawnCards, _ := original.DrawInto("hand", "alice", OwnerOnly, 2)
+	original.RecordDraw("alice", "hand", drawnCards)
+	original.Share("bob")
+	original.RecordShare("alice", []string{"bob"})
+
+	replayed, err := Replay(original.Guid, original.History)
+	if err != nil {
+		t.Fatalf("Expected history to be replayed, instead got %v", err)
+	}
+
+	if !cmp.Equal(replayed.Cards, original.Cards) {
+		t.Errorf("Expected replayed cards %v to equal %v", replayed.Cards, original.Cards)
+	}
+
+	if !cmp.Equal(replayed.Piles, original.Piles) {
+		t.Errorf("Expected replayed piles %v to equal %v", replayed.Piles, original.Piles)
+	}
+
+	if replayed.Owner != "alice" || !replayed.IsAccessibleBy("bob") {
+		t.Errorf("Expected replayed deck to belong to alice and be shared with bob")
+	}
+
+	if len(replayed.History) != 5 {
+		t.Errorf("Expected replayed deck to keep its 5 events, found %d", len(replayed.History))
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	deck.RecordDraw("", "", deck.Draw(10))
+	deck.RecordDraw("", "", deck.Draw(10))
+
+	before, err := deck.AsOf(2)
+	if err != nil {
+		t.Fatalf("Expected deck to be rebuilt as of event 2, instead got %v", err)
+	}
+
+	if before.RemainingCardCount() != 42 {
+		msg := "Expected 42 cards to remain after the first draw, found %d"
+		t.Errorf(msg, before.RemainingCardCount())
+	}
+
+	if _, err := deck.AsOf(4); err == nil {
+		t.Errorf("Expected err since there's no event 4")
+	}
+}
+
+func TestReplayTamperedHistory(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	e := deck.RecordDraw("", "", deck.Draw(1))
+	e.Cards = []string{"AS"}
+
+	_, err := Replay(deck.Guid, []Event{deck.History[0], e})
+	if err == nil {
+		t.Errorf("Expected err since the ace of spades was not on top of the deck")
+	}
+}
@@ -0,0 +1,66 @@
+package deck
+
+import (
+	"strings"
+)
+
+// how cards look in a terminal. Kept here so that every program printing
+// cards prints them the same way
+
+const (
+	red   = "\x1b[31m"
+	reset = "\x1b[0m"
+	// stands in for a card whose face can't be seen
+	CardBack = "🂠"
+)
+
+func (s Suit) Symbol() string {
+	switch s {
+	case Spades:
+		return "♠"
+	case Diamonds:
+		return "♦"
+	case Clubs:
+		return "♣"
+	case Hearts:
+		return "♥"
+	}
+	return "?"
+}
+
+func (s Suit) IsRed() bool {
+	return s == Hearts || s == Diamonds
+}
+
+// the corner of the card, as in A♠ or 10♥
+func (c *Card) Face() string {
+	code := c.Code()
+	return code[:len(code)-1] + c.Suit.Symbol()
+}
+
+// Face in red for hearts and diamonds. Black cards are left in the
+// terminal's own color, which may well be white on a dark background
+func (c *Card) ColoredFace() string {
+	if c.Suit.IsRed() {
+		return red + c.Face() + reset
+	}
+	return c.Face()
+}
+
+// faces separated by spaces, colored unless color is false
+func RenderCards(cards []Card, color bool) string {
+	faces := []string{}
+	for _, card := range cards {
+		if color {
+			faces = append(faces, card.ColoredFace())
+		} else {
+			faces = append(faces, card.Face())
+		}
+	}
+	return strings.Join(faces, " ")
+}
+
+// as many card backs as there are cards
+func RenderBacks(count int) string {
+	return strings.TrimSpace(strings.Repeat(CardBack+" ", count))
+}
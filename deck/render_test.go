@@ -0,0 +1,33 @@
+package deck
+
+import (
+	"testing"
+)
+
+func TestRenderCards(t *testing.T) {
+	cards := []Card{newCard(Ace, Spades), newCard(V10, Hearts), newCard(Queen, Diamonds)}
+
+	t.Run("renders faces with suit symbols", func(t *testing.T) {
+		rendered := RenderCards(cards, false)
+		if rendered != "A♠ 10♥ Q♦" {
+			t.Errorf("Expected A♠ 10♥ Q♦, found %q instead", rendered)
+		}
+	})
+
+	t.Run("colors red suits only", func(t *testing.T) {
+		rendered := RenderCards(cards, true)
+		expected := "A♠ \x1b[31m10♥\x1b[0m \x1b[31mQ♦\x1b[0m"
+		if rendered != expected {
+			t.Errorf("Expected %q, found %q instead", expected, rendered)
+		}
+	})
+
+	t.Run("renders backs of unseen cards", func(t *testing.T) {
+		if rendered := RenderBacks(2); rendered != CardBack+" "+CardBack {
+			t.Errorf("Expected 2 card backs, found %q instead", rendered)
+		}
+		if rendered := RenderBacks(0); rendered != "" {
+			t.Errorf("Expected no card backs, found %q instead", rendered)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package deck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// bump whenever the shape of Snapshot changes and teach ParseSnapshot how to
+// read the previous version
+const SnapshotVersion = 1
+
+// a deck written down in full (hidden cards included) so that it can be
+// moved to another server or attached to a bug report
+type Snapshot struct {
+	Version int       `json:"version"`
+	Guid    uuid.UUID `json:"deck_id"`
+	// not to be confused with Version, which is the version of the format
+	DeckVersion int                     `json:"deck_version"`
+	Cards       []string                `json:"cards"`
+	Shuffled    bool                    `json:"shuffled"`
+	Owner       string                  `json:"owner,omitempty"`
+	Visibility  string                  `json:"visibility"`
+	SharedWith  []string                `json:"shared_with,omitempty"`
+	Rules       string                  `json:"rules,omitempty"`
+	Piles       map[string]SnapshotPile `json:"piles,omitempty"`
+	History     []SnapshotEvent         `json:"history"`
+}
+
+type SnapshotPile struct {
+	Owner      string   `json:"owner,omitempty"`
+	Visibility string   `json:"visibility"`
+	Cards      []string `json:"cards"`
+}
+
+type SnapshotEvent struct {
+	Seq        int       `json:"seq"`
+	Type       EventType `json:"type"`
+	Actor      string    `json:"actor,omitempty"`
+	Time       time.Time `json:"time"`
+	Cards      []string  `json:"cards,omitempty"`
+	Pile       string    `json:"pile,omitempty"`
+	Owner      string    `json:"owner,omitempty"`
+	Visibility string    `json:"visibility"`
+	Players    []string  `json:"players,omitempty"`
+	Targets    []int     `json:"targets,omitempty"`
+}
+
+func (d *Deck) Snapshot() Snapshot {
+	piles := map[string]SnapshotPile{}
+	for name, pile := range d.Piles {
+		piles[name] = SnapshotPile{pile.Owner, pile.Visibility.String(), codes(pile.Cards)}
+	}
+
+	return Snapshot{
+		Version:     SnapshotVersion,
+		Guid:        d.Guid,
+		DeckVersion: d.Version,
+		Cards:       codes(d.Cards),
+		Shuffled:    d.IsShuffled(),
+		Owner:       d.Owner,
+		Visibility:  d.Visibility.String(),
+		SharedWith:  d.SharedWith,
+		Rules:       d.Rules,
+		Piles:       piles,
+		History:     intoSnapshotEvents(d.History),
+	}
+}
+
+func intoSnapshotEvents(events []Event) []SnapshotEvent {
+	snapshotEvents := []SnapshotEvent{}
+	for _, e := range events {
+		snapshotEvents = append(snapshotEvents, SnapshotEvent{
+			e.Seq,
+			e.Type,
+			e.Actor,
+			e.Time,
+			e.Cards,
+			e.Pile,
+			e.Owner,
+			e.Visibility.String(),
+			e.Players,
+			e.Targets,
+		})
+	}
+	return snapshotEvents
+}
+
+func fromSnapshotEvents(snapshotEvents []SnapshotEvent) ([]Event, error) {
+	events := []Event{}
+	for _, e := range snapshotEvents {
+		visibility, err := ParseVisibility(e.Visibility)
+		if err != nil {
+			return []Event{}, err
+		}
+		events = append(events, Event{
+			e.Seq,
+			e.Type,
+			e.Actor,
+			e.Time,
+			e.Cards,
+			e.Pile,
+			e.Owner,
+			visibility,
+			e.Players,
+			e.Targets,
+		})
+	}
+	return events, nil
+}
+
+// refuses snapshots of versions it doesn't know
+func ParseSnapshot(data []byte) (Snapshot, error) {
+	var versioned struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &versioned); err != nil {
+		return Snapshot{}, err
+	}
+
+	if versioned.Version != SnapshotVersion {
+		msg := fmt.Sprintf("Unsupported snapshot version %d", versioned.Version)
+		return Snapshot{}, errors.New(msg)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, err
+	}
+	return s, nil
+}
+
+// the history is the source of truth, the rest of the snapshot has to agree
+// with it or the snapshot is refused
+func (s Snapshot) Restore() (Deck, error) {
+	if s.Version != SnapshotVersion {
+		msg := fmt.Sprintf("Unsupported snapshot version %d", s.Version)
+		return NewEmptyDeck(), errors.New(msg)
+	}
+
+	history, err := fromSnapshotEvents(s.History)
+	if err != nil {
+		return NewEmptyDeck(), err
+	}
+
+	d, err := Replay(s.Guid, history)
+	if err != nil {
+		return NewEmptyDeck(), err
+	}
+	d.Rules = s.Rules
+
+	// every event bumps the version, and so did undoing before it was
+	// recorded, so it can only ever be ahead of the history
+	if s.DeckVersion < d.Version {
+		msg := fmt.Sprintf("Snapshot deck version %d is behind its %d events", s.DeckVersion, d.Version)
+		return NewEmptyDeck(), errors.New(msg)
+	}
+	d.Version = s.DeckVersion
+
+	replayed := d.Snapshot()
+	if !slices.Equal(replayed.Cards, s.Cards) {
+		return NewEmptyDeck(), errors.New("Snapshot cards do not match its history")
+	}
+
+	if len(replayed.Piles) != len(s.Piles) {
+		return NewEmptyDeck(), errors.New("Snapshot piles do not match its history")
+	}
+	for name, pile := range s.Piles {
+		other := replayed.Piles[name]
+		sameCards := slices.Equal(other.Cards, pile.Cards)
+		if !sameCards || other.Owner != pile.Owner || other.Visibility != pile.Visibility {
+			msg := fmt.Sprintf("Snapshot pile %v does not match its history", name)
+			return NewEmptyDeck(), errors.New(msg)
+		}
+	}
+	return d, nil
+}
+
+// the decks written down in path by WriteSnapshots, keyed by guid. A file
+// that doesn't exist yet holds no decks
+func ReadSnapshots(path string) (map[uuid.UUID]Deck, error) {
+	decks := map[uuid.UUID]Deck{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return decks, nil
+	}
+	if err != nil {
+		return decks, err
+	}
+
+	var snapshots map[uuid.UUID]json.RawMessage
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return decks, fmt.Errorf("Failed to read %v: %w", path, err)
+	}
+	for guid, raw := range snapshots {
+		snapshot, err := ParseSnapshot(raw)
+		if err != nil {
+			return decks, fmt.Errorf("Failed to read deck %v: %w", guid, err)
+		}
+		d, err := snapshot.Restore()
+		if err != nil {
+			return decks, fmt.Errorf("Failed to restore deck %v: %w", guid, err)
+		}
+		decks[guid] = d
+	}
+	return decks, nil
+}
+
+// written to a temporary file first so that a failed write doesn't take
+// every deck with it
+func WriteSnapshots(path string, decks map[uuid.UUID]Deck) error {
+	snapshots := map[uuid.UUID]Snapshot{}
+	for guid, d := range decks {
+		snapshots[guid] = d.Snapshot()
+	}
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
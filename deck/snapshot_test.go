@@ -0,0 +1,120 @@
+package deck
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	original := NewDefaultDeck()
+	original.Owner = "alice"
+	original.Visibility = FaceDown
+	original.Rules = "highcard"
+	original.RecordCreate("alice")
+	original.Shuffle()
+	original.RecordShuffle("alice")
+	drawnCards, _ := original.DrawInto("hand", "alice", OwnerOnly, 2)
+	original.RecordDraw("alice", "hand", drawnCards)
+	original.RecordDraw("alice", "", original.Draw(1))
+	original.Undo("", 1, nil)
+
+	data, _ := json.Marshal(original.Snapshot())
+	snapshot, err := ParseSnapshot(data)
+	if err != nil {
+		t.Fatalf("Expected snapshot to be parsed, instead got %v", err)
+	}
+
+	restored, err := snapshot.Restore()
+	if err != nil {
+		t.Fatalf("Expected snapshot to be restored, instead got %v", err)
+	}
+
+	if restored.Version != original.Version {
+		t.Errorf("Expected version %v to be restored, found %v", original.Version, restored.Version)
+	}
+
+	if restored.Guid != original.Guid || restored.Owner != "alice" || restored.Rules != "highcard" {
+		t.Errorf("Expected restored deck to keep its identity, found %v", restored)
+	}
+
+	if !cmp.Equal(restored.Cards, original.Cards) || !cmp.Equal(restored.Piles, original.Piles) {
+		t.Errorf("Expected restored deck to have the same cards and piles")
+	}
+
+	if len(restored.History) != 5 || len(restored.Undone()) != 1 {
+		t.Errorf("Expected history and undone events to be restored")
+	}
+
+	if _, err := restored.Redo("", 1); err != nil {
+		t.Errorf("Expected the undone draw to be redone, instead got %v", err)
+	}
+}
+
+func TestRestoreTamperedSnapshot(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	snapshot := deck.Snapshot()
+	snapshot.Cards = snapshot.Cards[1:]
+
+	if _, err := snapshot.Restore(); err == nil {
+		t.Errorf("Expected err since cards do not match history")
+	}
+
+	snapshot = deck.Snapshot()
+	snapshot.DeckVersion = 0
+	if _, err := snapshot.Restore(); err == nil {
+		t.Errorf("Expected err since the deck version is behind its history")
+	}
+}
+
+func TestParseUnsupportedSnapshot(t *testing.T) {
+	for _, data := range []string{`{"version":0}`, `{"version":2}`, `{"version":99}`} {
+		if _, err := ParseSnapshot([]byte(data)); err == nil {
+			t.Errorf("Expected err due to unsupported version in %v", data)
+		}
+	}
+}
+
+func TestWriteSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decks", "decks.json")
+
+	t.Run("reads no decks before anything was written", func(t *testing.T) {
+		decks, err := ReadSnapshots(path)
+		if err != nil || len(decks) != 0 {
+			t.Errorf("Expected no decks, found %v (%v)", decks, err)
+		}
+	})
+
+	t.Run("reads back what was written", func(t *testing.T) {
+		d := NewDefaultDeck()
+		d.RecordCreate("alice")
+		drawn, _ := d.DrawInto("hand", "alice", OwnerOnly, 2)
+		d.RecordDraw("alice", "hand", drawn)
+		if err := WriteSnapshots(path, map[uuid.UUID]Deck{d.Guid: d}); err != nil {
+			t.Fatalf("Expected the decks to be written, found %v", err)
+		}
+
+		decks, err := ReadSnapshots(path)
+		if err != nil {
+			t.Fatalf("Expected the decks to be read, found %v", err)
+		}
+		read := decks[d.Guid]
+		if diff := cmp.Diff(d.Snapshot(), read.Snapshot()); diff != "" {
+			t.Errorf("Expected the same deck back (-written +read):\n%s", diff)
+		}
+		if _, err := os.Stat(path + ".tmp"); err == nil {
+			t.Errorf("Expected the temporary file to be gone")
+		}
+	})
+
+	t.Run("refuses a file that isn't snapshots", func(t *testing.T) {
+		os.WriteFile(path, []byte("not json"), 0o644)
+		if _, err := ReadSnapshots(path); err == nil {
+			t.Errorf("Expected err since the file is garbage")
+		}
+	})
+}
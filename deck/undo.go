@@ -0,0 +1,171 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// decides whether an event may be taken back. Games usually forbid it once
+// the event showed someone cards they didn't know about
+type UndoPolicy func(e Event) error
+
+func (e Event) RevealsCards() bool {
+	return len(e.Cards) > 0 && (e.Visibility == Public || e.Visibility == OwnerOnly)
+}
+
+func ForbidUndoOfRevealed(e Event) error {
+	if e.RevealsCards() {
+		msg := fmt.Sprintf("Event %d revealed cards and cannot be undone", e.Seq)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// the events the deck is the outcome of, oldest first, and those undone
+// since that may still be redone, the next one to redo last. Whatever was
+// undone can no longer be redone once something else happens
+func (d *Deck) timeline() ([]Event, []Event) {
+	done := []Event{}
+	undone := []Event{}
+	for _, e := range d.History {
+		switch e.Type {
+		case Undone:
+			n := min(len(e.Targets), len(done))
+			for i := 0; i < n; i += 1 {
+				undone = append(undone, done[len(done)-1])
+				done = done[:len(done)-1]
+			}
+		case Redone:
+			n := min(len(e.Targets), len(undone))
+			for i := 0; i < n; i += 1 {
+				done = append(done, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			}
+		default:
+			done = append(done, e)
+			undone = []Event{}
+		}
+	}
+	return done, undone
+}
+
+// the events that may be redone, the next one to redo last
+func (d *Deck) Undone() []Event {
+	_, undone := d.timeline()
+	return undone
+}
+
+func seqs(events []Event) []int {
+	seqs := []int{}
+	for _, e := range events {
+		seqs = append(seqs, e.Seq)
+	}
+	return seqs
+}
+
+// takes back the last count events, as long as policy (if any) allows every
+// one of them, by recording that they were undone. The creation of a deck
+// can't be undone
+func (d *Deck) Undo(actor string, count int, policy UndoPolicy) ([]Event, error) {
+	done, _ := d.timeline()
+	n := len(done)
+	if count < 1 || count > n-1 {
+		msg := fmt.Sprintf("Cannot undo %d of the %d events of deck %v", count, n-1, d.Guid)
+		return []Event{}, errors.New(msg)
+	}
+
+	undone := slices.Clone(done[n-count:])
+	if policy != nil {
+		for _, e := range undone {
+			if err := policy(e); err != nil {
+				return []Event{}, err
+			}
+		}
+	}
+
+	targets := seqs(undone)
+	slices.Reverse(targets)
+	if err := d.rewind(Event{Type: Undone, Actor: actor, Visibility: Hidden, Targets: targets}); err != nil {
+		return []Event{}, err
+	}
+	return undone, nil
+}
+
+func (d *Deck) Redo(actor string, count int) ([]Event, error) {
+	_, undone := d.timeline()
+	n := len(undone)
+	if count < 1 || count > n {
+		msg := fmt.Sprintf("Cannot redo %d of the %d undone events of deck %v", count, n, d.Guid)
+		return []Event{}, errors.New(msg)
+	}
+
+	redone := slices.Clone(undone[n-count:])
+	slices.Reverse(redone)
+	if err := d.rewind(Event{Type: Redone, Actor: actor, Visibility: Hidden, Targets: seqs(redone)}); err != nil {
+		return []Event{}, err
+	}
+	return redone, nil
+}
+
+// replays the history with e on top, leaving the deck alone if e doesn't apply
+func (d *Deck) rewind(e Event) error {
+	e.Seq = len(d.History) + 1
+	e.Time = time.Now().UTC()
+
+	next, err := Replay(d.Guid, append(slices.Clone(d.History), e))
+	if err != nil {
+		return err
+	}
+	next.Rules = d.Rules
+	next.Version = d.Version + 1
+	*d = next
+	return nil
+}
+
+// rebuilds the deck from the events left once the targets of e are taken back
+func (d *Deck) applyUndo(e Event) error {
+	done, _ := d.timeline()
+	n := len(e.Targets)
+	if n < 1 || n > len(done)-1 {
+		msg := fmt.Sprintf("Event %d undoes %d of the %d events of deck %v", e.Seq, n, len(done)-1, d.Guid)
+		return errors.New(msg)
+	}
+	for i, seq := range e.Targets {
+		if done[len(done)-1-i].Seq != seq {
+			msg := fmt.Sprintf("Event %d undoes event %d which is not the last one done", e.Seq, seq)
+			return errors.New(msg)
+		}
+	}
+
+	past, err := Replay(d.Guid, done[:len(done)-n])
+	if err != nil {
+		return err
+	}
+	d.Cards = past.Cards
+	d.Owner = past.Owner
+	d.Visibility = past.Visibility
+	d.Piles = past.Piles
+	d.SharedWith = past.SharedWith
+	return nil
+}
+
+func (d *Deck) applyRedo(e Event) error {
+	_, undone := d.timeline()
+	if len(e.Targets) < 1 || len(e.Targets) > len(undone) {
+		msg := fmt.Sprintf("Event %d redoes %d of the %d undone events of deck %v", e.Seq, len(e.Targets), len(undone), d.Guid)
+		return errors.New(msg)
+	}
+	for i, seq := range e.Targets {
+		next := undone[len(undone)-1-i]
+		if next.Seq != seq {
+			msg := fmt.Sprintf("Event %d redoes event %d which is not the next one undone", e.Seq, seq)
+			return errors.New(msg)
+		}
+		if err := d.applyOutcome(next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package deck
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	deck.Shuffle()
+	deck.RecordShuffle("")
+	shuffledCards := append([]Card{}, deck.Cards...)
+	deck.RecordDraw("", "", deck.Draw(5))
+
+	undone, err := deck.Undo("", 1, nil)
+	if err != nil || len(undone) != 1 || undone[0].Type != Drawn {
+		t.Fatalf("Expected the draw to be undone, found %v (%v)", undone, err)
+	}
+
+	if !cmp.Equal(deck.Cards, shuffledCards) {
+		t.Errorf("Expected the drawn cards to be back on top of the deck")
+	}
+
+	redone, err := deck.Redo("", 1)
+	if err != nil || len(redone) != 1 || deck.RemainingCardCount() != 47 {
+		t.Errorf("Expected the draw to be redone, found %v (%v)", redone, err)
+	}
+
+	if _, err := deck.Redo("", 1); err == nil {
+		t.Errorf("Expected err since there's nothing left to redo")
+	}
+}
+
+func TestUndoSeveral(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	deck.RecordDraw("", "", deck.Draw(1))
+	deck.RecordDraw("", "", deck.Draw(2))
+
+	if _, err := deck.Undo("", 2, nil); err != nil || deck.RemainingCardCount() != 52 {
+		t.Errorf("Expected both draws to be undone, found %d cards (%v)", deck.RemainingCardCount(), err)
+	}
+
+	if _, err := deck.Undo("", 1, nil); err == nil {
+		t.Errorf("Expected err since the creation of a deck cannot be undone")
+	}
+
+	deck.Redo("", 1)
+	if deck.RemainingCardCount() != 51 {
+		t.Errorf("Expected redo to start with the oldest undone draw")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	deck := NewDefaultDeck()
+	versions := []int{deck.Version}
+	deck.RecordCreate("")
+	versions = append(versions, deck.Version)
+	deck.RecordDraw("", "", deck.Draw(1))
+	versions = append(versions, deck.Version)
+	deck.Undo("", 1, nil)
+	versions = append(versions, deck.Version)
+	deck.Redo("", 1)
+	versions = append(versions, deck.Version)
+
+	for i := 1; i < len(versions); i += 1 {
+		if versions[i] <= versions[i-1] {
+			t.Errorf("Expected every change to bump the version, found %v", versions)
+		}
+	}
+}
+
+func TestRecordClearsRedo(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	deck.RecordDraw("", "", deck.Draw(1))
+	deck.Undo("", 1, nil)
+	deck.RecordDraw("", "", deck.Draw(3))
+
+	if _, err := deck.Redo("", 1); err == nil {
+		t.Errorf("Expected err since a new draw happened after the undo")
+	}
+}
+
+func TestUndoKeepsHistory(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("alice")
+	deck.RecordDraw("alice", "", deck.Draw(2))
+	deck.Undo("bob", 1, nil)
+	deck.Shuffle()
+	deck.RecordShuffle("alice")
+
+	expected := []Event{
+		{Seq: 1, Type: Created},
+		{Seq: 2, Type: Drawn},
+		{Seq: 3, Type: Undone, Actor: "bob", Targets: []int{2}},
+		{Seq: 4, Type: Shuffled},
+	}
+	if len(deck.History) != len(expected) {
+		t.Fatalf("Expected every event to be kept, found %v", deck.History)
+	}
+	for i, e := range expected {
+		found := deck.History[i]
+		if found.Seq != e.Seq || found.Type != e.Type || (e.Actor != "" && found.Actor != e.Actor) || !cmp.Equal(found.Targets, e.Targets) {
+			t.Errorf("Expected %v, found %v", e, found)
+		}
+	}
+
+	replayed, err := Replay(deck.Guid, deck.History)
+	if err != nil || !cmp.Equal(replayed.Cards, deck.Cards) {
+		t.Errorf("Expected the history to replay into the same deck, found %v (%v)", replayed.Cards, err)
+	}
+
+	past, err := deck.AsOf(3)
+	if err != nil || past.RemainingCardCount() != 52 {
+		t.Errorf("Expected the draw to be undone as of event 3, found %d cards (%v)", past.RemainingCardCount(), err)
+	}
+}
+
+func TestReplayRefusesUnknownTargets(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+	deck.RecordDraw("", "", deck.Draw(1))
+	history := append(deck.History, Event{Seq: 3, Type: Undone, Targets: []int{1}})
+
+	if _, err := Replay(deck.Guid, history); err == nil {
+		t.Errorf("Expected err since event 1 is not the last one done")
+	}
+}
+
+func TestForbidUndoOfRevealed(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.Visibility = FaceDown
+	deck.RecordCreate("")
+	deck.Shuffle()
+	deck.RecordShuffle("")
+
+	if _, err := deck.Undo("", 1, ForbidUndoOfRevealed); err != nil {
+		t.Errorf("Expected a face down shuffle to be undoable, instead got %v", err)
+	}
+
+	deck.RecordDraw("alice", "", deck.Draw(1))
+	if _, err := deck.Undo("", 1, ForbidUndoOfRevealed); err == nil {
+		t.Errorf("Expected err since alice saw the card she drew")
+	}
+}
@@ -0,0 +1,195 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+)
+
+// who gets to see what is the whole point of most card games so every place
+// cards can be in (the deck itself, a hand, a discard pile) has a visibility
+type Visibility int64
+
+const (
+	// everyone sees the faces
+	Public Visibility = iota
+	// the owner sees the faces, everyone else only sees how many there are
+	OwnerOnly
+	// nobody sees the faces, not even the owner, but everyone can count them
+	FaceDown
+	// nobody sees anything at all
+	Hidden
+)
+
+func (v Visibility) String() string {
+	switch v {
+	case Public:
+		return "public"
+	case OwnerOnly:
+		return "owner"
+	case FaceDown:
+		return "facedown"
+	case Hidden:
+		return "hidden"
+	}
+	return "unknown visibility"
+}
+
+func ParseVisibility(visibility string) (Visibility, error) {
+	switch visibility {
+	case "public":
+		return Public, nil
+	case "owner":
+		return OwnerOnly, nil
+	case "facedown":
+		return FaceDown, nil
+	case "hidden":
+		return Hidden, nil
+	default:
+		{
+			msg := fmt.Sprintf("Invalid visibility: %v", visibility)
+			return 0, errors.New(msg)
+		}
+	}
+}
+
+func FacesVisibleTo(owner string, visibility Visibility, player string) bool {
+	if visibility == Public {
+		return true
+	}
+	// an empty owner means anyone may claim it so we must not treat every
+	// anonymous player as the owner
+	return visibility == OwnerOnly && owner != "" && owner == player
+}
+
+func CountVisibleTo(visibility Visibility) bool {
+	return visibility != Hidden
+}
+
+// whether player was ever kept from seeing any of the cards of the deck, in
+// the deck itself, in its piles or in its history. The owner of the deck is
+// no exception
+func (d *Deck) HidesCardsFrom(player string) bool {
+	if !d.FacesVisibleTo(player) {
+		return true
+	}
+	for _, pile := range d.Piles {
+		if !FacesVisibleTo(pile.Owner, pile.Visibility, player) {
+			return true
+		}
+	}
+	for _, e := range d.History {
+		if len(e.Cards) > 0 && !FacesVisibleTo(e.Owner, e.Visibility, player) {
+			return true
+		}
+	}
+	return false
+}
+
+type Pile struct {
+	Owner      string
+	Visibility Visibility
+	Cards      []Card
+}
+
+func NewPile(owner string, visibility Visibility) Pile {
+	return Pile{owner, visibility, []Card{}}
+}
+
+// what a single player is allowed to know about a pile. Count is nil when
+// even the number of cards is unknown and Cards is nil when faces are unknown
+type PileView struct {
+	Owner      string
+	Visibility Visibility
+	Count      *int
+	Cards      []Card
+}
+
+func (p *Pile) ViewFor(player string) PileView {
+	view := PileView{p.Owner, p.Visibility, nil, nil}
+	if CountVisibleTo(p.Visibility) {
+		count := len(p.Cards)
+		view.Count = &count
+	}
+	if FacesVisibleTo(p.Owner, p.Visibility, player) {
+		view.Cards = p.Cards
+	}
+	return view
+}
+
+func (d *Deck) FacesVisibleTo(player string) bool {
+	return FacesVisibleTo(d.Owner, d.Visibility, player)
+}
+
+// piles are created on demand and the first one to put cards into a pile
+// decides who owns it and how visible it is. Like DrawExactly it draws all
+// of count or nothing
+func (d *Deck) DrawInto(name string, owner string, visibility Visibility, count int) ([]Card, error) {
+	pile, ok := d.Piles[name]
+	if !ok {
+		pile = NewPile(owner, visibility)
+	}
+
+	if pile.Owner != owner {
+		return []Card{}, fmt.Errorf("%w: %v belongs to %v", ErrForeignPile, name, pile.Owner)
+	}
+
+	cards, err := d.DrawExactly(count)
+	if err != nil {
+		return []Card{}, err
+	}
+	pile.Cards = append(pile.Cards, cards...)
+	d.Piles[name] = pile
+	return cards, nil
+}
+
+// draws count cards or nothing, into the pile of player when one is named
+// (see DrawInto) and off the deck otherwise
+func (d *Deck) DrawFor(player string, pile string, visibility Visibility, count int) ([]Card, error) {
+	if pile == "" {
+		return d.DrawExactly(count)
+	}
+	return d.DrawInto(pile, player, visibility, count)
+}
+
+// deals count cards to each of players, one at a time and in turn as dealers
+// do, into a pile named after and owned by each player. Either everyone gets
+// their cards or nothing is dealt. Every card dealt is an event of its own so
+// that the history replays in the order the cards were dealt
+func (d *Deck) Deal(actor string, players []string, count int, visibility Visibility) ([]Event, error) {
+	if count < 1 || len(players) == 0 {
+		return []Event{}, fmt.Errorf("%w: cannot deal %d cards to %d players", ErrInvalidCount, count, len(players))
+	}
+	// divided rather than multiplied so that a huge count can't overflow
+	if count > d.RemainingCardCount()/len(players) {
+		needed := math.MaxInt
+		if count <= math.MaxInt/len(players) {
+			needed = count * len(players)
+		}
+		return []Event{}, &NotEnoughCardsError{needed, d.RemainingCardCount()}
+	}
+	for _, player := range players {
+		if pile, ok := d.Piles[player]; ok && pile.Owner != player {
+			return []Event{}, fmt.Errorf("%w: %v belongs to %v", ErrForeignPile, player, pile.Owner)
+		}
+	}
+
+	// dealt into a copy of the piles, which callers may share with the deck
+	// they got this one from, and only kept once everyone got their cards
+	dealt := *d
+	dealt.Piles = map[string]Pile{}
+	maps.Copy(dealt.Piles, d.Piles)
+	events := []Event{}
+	for round := 0; round < count; round += 1 {
+		for _, player := range players {
+			cards, err := dealt.DrawInto(player, player, visibility, 1)
+			if err != nil {
+				return []Event{}, err
+			}
+			events = append(events, dealt.RecordDraw(actor, player, cards))
+		}
+	}
+	*d = dealt
+	return events, nil
+}
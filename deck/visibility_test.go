@@ -0,0 +1,186 @@
+package deck
+
+import (
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"math"
+	"testing"
+)
+
+func TestPileViewFor(t *testing.T) {
+	cards := []Card{newCard(Ace, Spades), newCard(King, Hearts)}
+
+	t.Run("public pile shows everything", func(t *testing.T) {
+		pile := Pile{"alice", Public, cards}
+		view := pile.ViewFor("bob")
+		if view.Count == nil || *view.Count != 2 || len(view.Cards) != 2 {
+			t.Errorf("Expected bob to see both cards of a public pile, found %v", view)
+		}
+	})
+
+	t.Run("owner only pile shows faces to owner", func(t *testing.T) {
+		pile := Pile{"alice", OwnerOnly, cards}
+		if view := pile.ViewFor("alice"); len(view.Cards) != 2 {
+			t.Errorf("Expected alice to see her own cards, found %v", view)
+		}
+
+		view := pile.ViewFor("bob")
+		if view.Cards != nil {
+			t.Errorf("Expected bob not to see alice's cards, found %v", view.Cards)
+		}
+		if view.Count == nil || *view.Count != 2 {
+			t.Errorf("Expected bob to be able to count alice's cards")
+		}
+	})
+
+	t.Run("owner only pile without owner shows no faces", func(t *testing.T) {
+		pile := Pile{"", OwnerOnly, cards}
+		if view := pile.ViewFor(""); view.Cards != nil {
+			t.Errorf("Expected anonymous player not to see cards, found %v", view.Cards)
+		}
+	})
+
+	t.Run("face down pile only shows count", func(t *testing.T) {
+		pile := Pile{"alice", FaceDown, cards}
+		view := pile.ViewFor("alice")
+		if view.Cards != nil || view.Count == nil {
+			t.Errorf("Expected only the count of a face down pile, found %v", view)
+		}
+	})
+
+	t.Run("hidden pile shows nothing", func(t *testing.T) {
+		pile := Pile{"alice", Hidden, cards}
+		view := pile.ViewFor("alice")
+		if view.Cards != nil || view.Count != nil {
+			t.Errorf("Expected nothing of a hidden pile, found %v", view)
+		}
+	})
+}
+
+func TestDrawInto(t *testing.T) {
+	deck := NewDefaultDeck()
+	drawnCards, err := deck.DrawInto("hand", "alice", OwnerOnly, 2)
+	if err != nil {
+		t.Fatalf("Expected cards to be drawn into pile, instead got %v", err)
+	}
+
+	pile := deck.Piles["hand"]
+	if len(drawnCards) != 2 || len(pile.Cards) != 2 {
+		t.Errorf("Expected 2 cards to be in alice's hand, found %v", pile.Cards)
+	}
+
+	_, err = deck.DrawInto("hand", "alice", OwnerOnly, 51)
+	if !errors.Is(err, ErrNotEnoughCards) {
+		t.Errorf("Expected err since only 50 cards are left, found %v", err)
+	}
+
+	_, err = deck.DrawInto("hand", "bob", OwnerOnly, 1)
+	if !errors.Is(err, ErrForeignPile) {
+		t.Errorf("Expected err since bob does not own alice's hand")
+	}
+
+	if deck.RemainingCardCount() != 50 {
+		t.Errorf("Expected deck to have 50 cards, found %d", deck.RemainingCardCount())
+	}
+}
+
+func TestDrawFor(t *testing.T) {
+	deck := NewDefaultDeck()
+	if _, err := deck.DrawFor("alice", "", OwnerOnly, 2); err != nil || len(deck.Piles) != 0 {
+		t.Errorf("Expected cards drawn off the deck, found %v (%v)", deck.Piles, err)
+	}
+	if _, err := deck.DrawFor("alice", "hand", Public, 3); err != nil || len(deck.Piles["hand"].Cards) != 3 {
+		t.Errorf("Expected 3 cards in alice's hand, found %v (%v)", deck.Piles["hand"], err)
+	}
+	if _, err := deck.DrawFor("alice", "", OwnerOnly, 48); !errors.Is(err, ErrNotEnoughCards) || deck.RemainingCardCount() != 47 {
+		t.Errorf("Expected nothing drawn since only 47 cards are left, found %v", err)
+	}
+}
+
+func TestParseVisibility(t *testing.T) {
+	for _, v := range []Visibility{Public, OwnerOnly, FaceDown, Hidden} {
+		parsed, err := ParseVisibility(v.String())
+		if err != nil || parsed != v {
+			t.Errorf("Expected %v to be parsed back, found %v (%v)", v, parsed, err)
+		}
+	}
+}
+
+func TestHidesCardsFrom(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.Owner = "dealer"
+	deck.RecordCreate("dealer")
+	if deck.HidesCardsFrom("") {
+		t.Errorf("Expected a public deck to hide nothing")
+	}
+
+	cards, _ := deck.DrawInto("table", "", Public, 2)
+	deck.RecordDraw("", "table", cards)
+	if deck.HidesCardsFrom("") {
+		t.Errorf("Expected a public pile to hide nothing")
+	}
+
+	cards, _ = deck.DrawInto("hand", "alice", OwnerOnly, 1)
+	deck.RecordDraw("alice", "hand", cards)
+	if deck.HidesCardsFrom("alice") {
+		t.Errorf("Expected alice to see her own hand")
+	}
+	if !deck.HidesCardsFrom("bob") || !deck.HidesCardsFrom("dealer") {
+		t.Errorf("Expected alice's hand to be hidden from everyone else, the owner of the deck included")
+	}
+
+	deck.Visibility = FaceDown
+	if !deck.HidesCardsFrom("alice") {
+		t.Errorf("Expected a face down deck to hide its order")
+	}
+}
+
+func TestDeal(t *testing.T) {
+	deck := NewDeck([]Card{newCard(Ace, Spades), newCard(King, Hearts), newCard(Queen, Clubs), newCard(Jack, Diamonds), newCard(V10, Hearts)})
+	deck.RecordCreate("dealer")
+
+	events, err := deck.Deal("dealer", []string{"alice", "bob"}, 2, OwnerOnly)
+	if err != nil || len(events) != 4 {
+		t.Fatalf("Expected a card per event, found %v (%v)", events, err)
+	}
+
+	alice, bob := deck.Piles["alice"], deck.Piles["bob"]
+	if alice.Owner != "alice" || bob.Owner != "bob" || alice.Visibility != OwnerOnly {
+		t.Errorf("Expected every player to own their pile, found %v and %v", alice, bob)
+	}
+	if codes(alice.Cards)[0] != "10H" || codes(bob.Cards)[0] != "JD" || codes(alice.Cards)[1] != "QC" {
+		t.Errorf("Expected the cards to be dealt in turn, found %v and %v", codes(alice.Cards), codes(bob.Cards))
+	}
+	if alice.ViewFor("dealer").Cards != nil {
+		t.Errorf("Expected the dealer not to see alice's hand")
+	}
+
+	if replayed, err := Replay(deck.Guid, deck.History); err != nil || !cmp.Equal(replayed.Piles, deck.Piles) {
+		t.Errorf("Expected the deal to replay, found %v (%v)", replayed.Piles, err)
+	}
+}
+
+func TestDealAllOrNothing(t *testing.T) {
+	deck := NewDefaultDeck()
+	deck.RecordCreate("")
+
+	if _, err := deck.Deal("", []string{"alice", "bob"}, 27, OwnerOnly); !errors.Is(err, ErrNotEnoughCards) {
+		t.Errorf("Expected ErrNotEnoughCards, found %v", err)
+	}
+
+	huge := math.MaxInt/2 + 1
+	if _, err := deck.Deal("", []string{"alice", "bob"}, huge, OwnerOnly); !errors.Is(err, ErrNotEnoughCards) {
+		t.Errorf("Expected ErrNotEnoughCards for a count that overflows, found %v", err)
+	}
+	if deck.RemainingCardCount() != 52 || len(deck.Piles) != 0 {
+		t.Errorf("Expected nothing to be dealt, found %d cards and %v", deck.RemainingCardCount(), deck.Piles)
+	}
+
+	deck.DrawInto("bob", "mallory", Public, 1)
+	if _, err := deck.Deal("", []string{"alice", "bob"}, 1, OwnerOnly); !errors.Is(err, ErrForeignPile) {
+		t.Errorf("Expected ErrForeignPile, found %v", err)
+	}
+	if deck.RemainingCardCount() != 51 || len(deck.Piles) != 1 {
+		t.Errorf("Expected nothing to be dealt, found %d cards and %v", deck.RemainingCardCount(), deck.Piles)
+	}
+}
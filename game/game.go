@@ -0,0 +1,254 @@
+// A framework for turn-based card games played on top of package deck
+package game
+
+import (
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+)
+
+type PlayerID string
+
+type Phase string
+
+// the framework only cares about whether a game is still being played.
+// Rules are free to come up with finer grained phases of their own
+// (i.e. "bidding") as long as they move to Finished once the game is over
+const (
+	Playing  Phase = "playing"
+	Finished Phase = "finished"
+)
+
+// everything a game needs to keep track of. Cards live either in the stock
+// or in named piles (hands, the table, a discard pile) whose visibility
+// decides who gets to see them. Rules that need more can stash it in Data
+type State struct {
+	Players []PlayerID
+	Turn    int
+	Phase   Phase
+	Stock   deck.Deck
+	Piles   map[string]deck.Pile
+	Data    any
+}
+
+func (s *State) CurrentPlayer() PlayerID {
+	return s.Players[s.Turn]
+}
+
+func (s *State) NextTurn() {
+	s.Turn = (s.Turn + 1) % len(s.Players)
+}
+
+func (s *State) IsSeated(player PlayerID) bool {
+	for _, p := range s.Players {
+		if p == player {
+			return true
+		}
+	}
+	return false
+}
+
+// by convention every player holds their cards in a pile named after them
+func HandOf(player PlayerID) string {
+	return fmt.Sprintf("hand:%s", player)
+}
+
+func (s *State) Hand(player PlayerID) []deck.Card {
+	return s.Piles[HandOf(player)].Cards
+}
+
+func (s *State) MoveCard(card deck.Card, from string, to string) error {
+	source := s.Piles[from]
+	remaining := []deck.Card{}
+	for _, c := range source.Cards {
+		if c != card {
+			remaining = append(remaining, c)
+		}
+	}
+
+	if len(remaining) == len(source.Cards) {
+		msg := fmt.Sprintf("There's no %v in %v", card.Code(), from)
+		return errors.New(msg)
+	}
+
+	source.Cards = remaining
+	s.Piles[from] = source
+	destination := s.Piles[to]
+	destination.Cards = append(destination.Cards, card)
+	s.Piles[to] = destination
+	return nil
+}
+
+type Action struct {
+	Type  string   `json:"type"`
+	Cards []string `json:"cards,omitempty"`
+}
+
+// a view is what a single player is allowed to know about the game. Each
+// pile is filtered according to its visibility
+type View struct {
+	Player    PlayerID                 `json:"player"`
+	Players   []PlayerID               `json:"players"`
+	Turn      PlayerID                 `json:"turn"`
+	Phase     Phase                    `json:"phase"`
+	Piles     map[string]deck.PileView `json:"piles"`
+	Remaining int                      `json:"remaining"`
+	Legal     []Action                 `json:"legal_actions"`
+	Scores    map[PlayerID]int         `json:"scores,omitempty"`
+	Extra     any                      `json:"extra,omitempty"`
+}
+
+// implementing this interface (and registering it) is all that is needed
+// for a new game to be hosted by the server
+type Rules interface {
+	Setup(players []PlayerID) (*State, error)
+	LegalActions(s *State, player PlayerID) []Action
+	Apply(s *State, player PlayerID, action Action) error
+	IsTerminal(s *State) bool
+	Score(s *State) map[PlayerID]int
+	ViewFor(s *State, player PlayerID) View
+}
+
+// rules may forbid taking back operations on decks they govern, typically
+// once hidden information was revealed. Rules that don't implement it allow
+// anything to be undone
+type UndoGuard interface {
+	CanUndo(e deck.Event) error
+}
+
+func UndoPolicyFor(gameType string) (deck.UndoPolicy, error) {
+	factory, ok := registry[gameType]
+	if !ok {
+		msg := fmt.Sprintf("There's no game of type %v", gameType)
+		return nil, errors.New(msg)
+	}
+
+	guard, ok := factory().(UndoGuard)
+	if !ok {
+		return nil, nil
+	}
+	return guard.CanUndo, nil
+}
+
+// most games render the same view so rules can delegate to this one
+func DefaultView(r Rules, s *State, player PlayerID) View {
+	piles := map[string]deck.PileView{}
+	for name, pile := range s.Piles {
+		piles[name] = pile.ViewFor(string(player))
+	}
+
+	view := View{
+		Player:    player,
+		Players:   s.Players,
+		Turn:      s.CurrentPlayer(),
+		Phase:     s.Phase,
+		Piles:     piles,
+		Remaining: s.Stock.RemainingCardCount(),
+		Legal:     r.LegalActions(s, player),
+	}
+	if r.IsTerminal(s) {
+		view.Scores = r.Score(s)
+	}
+	return view
+}
+
+var registry = map[string]func() Rules{}
+
+// meant to be called from the init function of the file defining the rules
+func Register(gameType string, factory func() Rules) {
+	if _, ok := registry[gameType]; ok {
+		panic(fmt.Sprintf("game %s registered twice", gameType))
+	}
+	registry[gameType] = factory
+}
+
+func Types() []string {
+	types := []string{}
+	for gameType := range registry {
+		types = append(types, gameType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+type Session struct {
+	Guid  uuid.UUID
+	Type  string
+	Rules Rules
+	State *State
+}
+
+func NewSession(gameType string, players []PlayerID) (*Session, error) {
+	factory, ok := registry[gameType]
+	if !ok {
+		msg := fmt.Sprintf("There's no game of type %v", gameType)
+		return nil, errors.New(msg)
+	}
+
+	if len(players) == 0 {
+		return nil, errors.New("A game needs at least one player")
+	}
+
+	rules := factory()
+	state, err := rules.Setup(players)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{uuid.New(), gameType, rules, state}, nil
+}
+
+// the framework enforces turn order and legality so that rules only have
+// to deal with the happy path in Apply
+func (s *Session) Act(player PlayerID, action Action) error {
+	if !s.State.IsSeated(player) {
+		msg := fmt.Sprintf("Player %v is not seated at this game", player)
+		return errors.New(msg)
+	}
+
+	if s.Rules.IsTerminal(s.State) {
+		return errors.New("The game is already over")
+	}
+
+	if !s.isLegal(player, action) {
+		msg := fmt.Sprintf("Action %v is not legal for player %v", action, player)
+		return errors.New(msg)
+	}
+
+	err := s.Rules.Apply(s.State, player, action)
+	if err != nil {
+		return err
+	}
+
+	if s.Rules.IsTerminal(s.State) {
+		s.State.Phase = Finished
+	}
+	return nil
+}
+
+func (s *Session) isLegal(player PlayerID, action Action) bool {
+	for _, legal := range s.Rules.LegalActions(s.State, player) {
+		if sameAction(legal, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAction(a, b Action) bool {
+	if a.Type != b.Type || len(a.Cards) != len(b.Cards) {
+		return false
+	}
+	for i := range a.Cards {
+		if a.Cards[i] != b.Cards[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Session) ViewFor(player PlayerID) View {
+	return s.Rules.ViewFor(s.State, player)
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"example.com/deck"
+	"testing"
+)
+
+func TestNewSession(t *testing.T) {
+	session, err := NewSession("highcard", []PlayerID{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("Expected session to be created, instead got %v", err)
+	}
+
+	if session.State.CurrentPlayer() != "alice" {
+		t.Errorf("Expected first seat to play first")
+	}
+
+	n := session.State.Stock.RemainingCardCount()
+	if n != 52-10 {
+		t.Errorf("Expected 42 cards to remain after dealing, found %d instead", n)
+	}
+}
+
+func TestNewSessionOfUnknownType(t *testing.T) {
+	_, err := NewSession("poker-but-not-really", []PlayerID{"alice"})
+	if err == nil {
+		t.Errorf("Expected err due to unknown game type")
+	}
+}
+
+func TestActOutOfTurn(t *testing.T) {
+	session, _ := NewSession("highcard", []PlayerID{"alice", "bob"})
+	card := session.State.Hand("bob")[0]
+	err := session.Act("bob", Action{"play", []string{card.Code()}})
+	if err == nil {
+		t.Errorf("Expected err since it's not bob's turn")
+	}
+}
+
+func TestActWithCardNotInHand(t *testing.T) {
+	session, _ := NewSession("highcard", []PlayerID{"alice", "bob"})
+	card := session.State.Hand("bob")[0]
+	err := session.Act("alice", Action{"play", []string{card.Code()}})
+	if err == nil {
+		t.Errorf("Expected err since alice does not hold %v", card)
+	}
+}
+
+func TestPlayUntilTerminal(t *testing.T) {
+	session, _ := NewSession("highcard", []PlayerID{"alice", "bob"})
+	for !session.Rules.IsTerminal(session.State) {
+		player := session.State.CurrentPlayer()
+		action := session.Rules.LegalActions(session.State, player)[0]
+		if err := session.Act(player, action); err != nil {
+			t.Fatalf("Expected legal action to be applied, instead got %v", err)
+		}
+	}
+
+	if session.State.Phase != Finished {
+		t.Errorf("Expected game to be finished, found %v instead", session.State.Phase)
+	}
+
+	scores := session.Rules.Score(session.State)
+	if scores["alice"]+scores["bob"] != 5 {
+		t.Errorf("Expected 5 tricks to have been taken, found %v instead", scores)
+	}
+}
+
+func TestViewHidesOpponentsHand(t *testing.T) {
+	session, _ := NewSession("highcard", []PlayerID{"alice", "bob"})
+	view := session.ViewFor("alice")
+
+	if len(view.Piles[HandOf("alice")].Cards) != 5 {
+		t.Errorf("Expected alice to see her own 5 cards")
+	}
+
+	bobsHand := view.Piles[HandOf("bob")]
+	if bobsHand.Cards != nil {
+		t.Errorf("Expected alice's view not to contain bob's cards %v", bobsHand.Cards)
+	}
+
+	if bobsHand.Count == nil || *bobsHand.Count != 5 {
+		t.Errorf("Expected alice to see bob holds 5 cards, found %v", bobsHand.Count)
+	}
+}
+
+func TestUndoPolicyFor(t *testing.T) {
+	policy, err := UndoPolicyFor("highcard")
+	if err != nil || policy == nil {
+		t.Fatalf("Expected highcard to guard undo, found %v", err)
+	}
+
+	d := deck.NewDefaultDeck()
+	d.RecordCreate("")
+	e := d.RecordDraw("alice", "", d.Draw(1))
+	if policy(e) == nil {
+		t.Errorf("Expected highcard to forbid undoing a draw alice saw")
+	}
+
+	if _, err := UndoPolicyFor("nope"); err == nil {
+		t.Errorf("Expected err due to unknown game type")
+	}
+}
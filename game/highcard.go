@@ -0,0 +1,131 @@
+package game
+
+import (
+	"errors"
+	"example.com/deck"
+	"fmt"
+)
+
+// every player is dealt the same number of cards and, in turn, plays one of
+// them onto the table. Once everyone has played the highest card takes the
+// trick. Whoever takes the most tricks wins
+type HighCard struct {
+	HandSize int
+}
+
+type highCardData struct {
+	Tricks map[PlayerID]int
+}
+
+func init() {
+	Register("highcard", func() Rules {
+		return &HighCard{HandSize: 5}
+	})
+}
+
+func (h *HighCard) Setup(players []PlayerID) (*State, error) {
+	stock := deck.NewDefaultDeck()
+	if len(players)*h.HandSize > stock.RemainingCardCount() {
+		msg := fmt.Sprintf("Not enough cards to deal to %d players", len(players))
+		return nil, errors.New(msg)
+	}
+
+	stock.Shuffle()
+	stock.Visibility = deck.FaceDown
+	piles := map[string]deck.Pile{"table": deck.NewPile("", deck.Public)}
+	tricks := map[PlayerID]int{}
+	for _, player := range players {
+		hand := deck.NewPile(string(player), deck.OwnerOnly)
+		hand.Cards = stock.Draw(h.HandSize)
+		piles[HandOf(player)] = hand
+		tricks[player] = 0
+	}
+
+	return &State{
+		Players: players,
+		Phase:   Playing,
+		Stock:   stock,
+		Piles:   piles,
+		Data:    &highCardData{tricks},
+	}, nil
+}
+
+func (h *HighCard) LegalActions(s *State, player PlayerID) []Action {
+	actions := []Action{}
+	if h.IsTerminal(s) || s.CurrentPlayer() != player {
+		return actions
+	}
+	for _, card := range s.Hand(player) {
+		actions = append(actions, Action{"play", []string{card.Code()}})
+	}
+	return actions
+}
+
+func (h *HighCard) Apply(s *State, player PlayerID, action Action) error {
+	card, err := deck.ParseCard(action.Cards[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.MoveCard(card, HandOf(player), "table")
+	if err != nil {
+		return err
+	}
+	s.NextTurn()
+
+	if len(s.Piles["table"].Cards) == len(s.Players) {
+		h.settleTrick(s)
+	}
+	return nil
+}
+
+// the table holds one card per player in turn order, and since the trick
+// always starts with the first seat the indices line up
+func (h *HighCard) settleTrick(s *State) {
+	table := s.Piles["table"]
+	winner := 0
+	for i, card := range table.Cards {
+		if beats(card, table.Cards[winner]) {
+			winner = i
+		}
+	}
+	data := s.Data.(*highCardData)
+	data.Tricks[s.Players[winner]] += 1
+	table.Cards = []deck.Card{}
+	s.Piles["table"] = table
+}
+
+// ranks decide first and suits break ties (Spades > Diamonds > Clubs > Hearts)
+func beats(card, other deck.Card) bool {
+	if card.Rank == other.Rank {
+		return card.Suit > other.Suit
+	}
+	return card.Rank > other.Rank
+}
+
+func (h *HighCard) IsTerminal(s *State) bool {
+	for _, player := range s.Players {
+		if len(s.Hand(player)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *HighCard) Score(s *State) map[PlayerID]int {
+	data := s.Data.(*highCardData)
+	scores := map[PlayerID]int{}
+	for player, tricks := range data.Tricks {
+		scores[player] = tricks
+	}
+	return scores
+}
+
+// once a card has been seen there's no taking it back
+func (h *HighCard) CanUndo(e deck.Event) error {
+	return deck.ForbidUndoOfRevealed(e)
+}
+
+func (h *HighCard) ViewFor(s *State, player PlayerID) View {
+	return DefaultView(h, s, player)
+}
@@ -14,7 +14,7 @@ const App = () => {
     const baseUrl = "http://localhost:8000"
 
     const handleCreate = () => {
-        var url = addQueryParams(`${baseUrl}/create`)
+        var url = addQueryParams(`${baseUrl}/v1/decks`)
 
         const promise = axios.post(url)
         promise
@@ -48,7 +48,7 @@ const App = () => {
     }
 
     const handleOpen = () => {
-        var url = `${baseUrl}/open/${guid}`
+        var url = `${baseUrl}/v1/decks/${guid}`
 
         const promise = axios.get(url)
         promise
@@ -61,9 +61,9 @@ const App = () => {
     }
 
     const handleDraw = () => {
-        var url = addQueryParams(`${baseUrl}/draw/${guid}`)
+        var url = addQueryParams(`${baseUrl}/v1/decks/${guid}/draws`)
 
-        const promise = axios.patch(url)
+        const promise = axios.post(url)
         promise
             .then((res) => {
                 setDrawRes(JSON.stringify(res.data))
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokens are plain HS256 JWTs so they can be minted by whatever issues them
+// (any JWT library will do) as long as it shares the secret with us. Only
+// the sub and exp claims are looked at
+type Authenticator struct {
+	secret  []byte
+	apiKeys map[string]string
+}
+
+func NewAuthenticator(secret []byte, apiKeys map[string]string) *Authenticator {
+	if len(secret) == 0 && len(apiKeys) == 0 {
+		panic("authenticator needs either a secret or api keys!")
+	}
+	return &Authenticator{secret, apiKeys}
+}
+
+type principalKey struct{}
+
+type claims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (a *Authenticator) Sign(principal string, expiresAt time.Time) (string, error) {
+	payload, err := json.Marshal(claims{principal, expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + a.signature(unsigned), nil
+}
+
+func (a *Authenticator) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a *Authenticator) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(a.secret) == 0 || len(parts) != 3 {
+		return "", errors.New("Malformed token")
+	}
+
+	// the header is part of what's signed so checking the signature is enough
+	// to reject tokens claiming another algorithm (i.e. "none")
+	unsigned := parts[0] + "." + parts[1]
+	if parts[0] != jwtHeader || !hmac.Equal([]byte(parts[2]), []byte(a.signature(unsigned))) {
+		return "", errors.New("Invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return "", err
+	}
+
+	if c.Subject == "" {
+		return "", errors.New("Token has no subject")
+	}
+
+	if time.Now().Unix() >= c.ExpiresAt {
+		return "", errors.New("Token has expired")
+	}
+	return c.Subject, nil
+}
+
+// Authorization: Bearer <jwt> or X-API-Key: <key>. Browsers can't set headers
+// when opening a WebSocket so the token may also come as ?access_token=<jwt>
+func (a *Authenticator) Authenticate(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("access_token")
+	}
+	return a.AuthenticateCredentials(r.Header.Get("X-API-Key"), token)
+}
+
+// an api key, when given, takes precedence over the token
+func (a *Authenticator) AuthenticateCredentials(key string, token string) (string, error) {
+	if key != "" {
+		for known, principal := range a.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+				return principal, nil
+			}
+		}
+		return "", errors.New("Unknown API key")
+	}
+
+	if token == "" {
+		return "", errors.New("Missing credentials")
+	}
+	return a.Verify(token)
+}
+
+// when no authenticator is configured the server stays as open as it used
+// to be and players simply tell us who they are
+func (ctx *HandlerContext) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ctx.auth == nil {
+			next(w, r)
+			return
+		}
+
+		principal, err := ctx.auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cards"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+		next(w, r)
+	}
+}
+
+func requestingPlayer(r *http.Request) string {
+	if principal, ok := r.Context().Value(principalKey{}).(string); ok {
+		return principal
+	}
+	return r.URL.Query().Get("player")
+}
+
+func authorize(r *http.Request, d *deck.Deck) error {
+	return authorizePlayer(requestingPlayer(r), d)
+}
+
+func authorizePlayer(player string, d *deck.Deck) error {
+	if d.IsAccessibleBy(player) {
+		return nil
+	}
+	msg := fmt.Sprintf("%v may not access deck %v", player, d.Guid)
+	return errors.New(msg)
+}
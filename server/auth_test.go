@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	auth := NewAuthenticator([]byte("s3cr3t"), nil)
+
+	t.Run("accepts signed token", func(t *testing.T) {
+		token, _ := auth.Sign("alice", time.Now().Add(time.Hour))
+		principal, err := auth.Verify(token)
+		if err != nil || principal != "alice" {
+			t.Errorf("Expected token to belong to alice, found %v (%v)", principal, err)
+		}
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		token, _ := auth.Sign("alice", time.Now().Add(-time.Second))
+		if _, err := auth.Verify(token); err == nil {
+			t.Errorf("Expected err due to expired token")
+		}
+	})
+
+	t.Run("rejects token signed with another secret", func(t *testing.T) {
+		other := NewAuthenticator([]byte("not-our-secret"), nil)
+		token, _ := other.Sign("alice", time.Now().Add(time.Hour))
+		if _, err := auth.Verify(token); err == nil {
+			t.Errorf("Expected err due to invalid signature")
+		}
+	})
+
+	t.Run("rejects unsigned token", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice","exp":9999999999}`))
+		if _, err := auth.Verify(header + "." + payload + "."); err == nil {
+			t.Errorf("Expected err due to unsigned token")
+		}
+	})
+}
+
+func TestAuthenticatedDeckAccess(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.auth = NewAuthenticator([]byte("s3cr3t"), map[string]string{"carols-key": "carol"})
+
+	alice, _ := ctx.auth.Sign("alice", time.Now().Add(time.Hour))
+	bob, _ := ctx.auth.Sign("bob", time.Now().Add(time.Hour))
+	asAlice := map[string]string{"Authorization": "Bearer " + alice}
+	asBob := map[string]string{"Authorization": "Bearer " + bob}
+
+	created := sendRequest(ctx, http.MethodPost, "/create", asAlice, "")
+	if created.Code != http.StatusCreated {
+		t.Fatalf("Expected alice to create a deck, found %v", created.Code)
+	}
+	guid := decodeCreatedDeck(t, created.Body.String()).Guid
+
+	t.Run("rejects missing credentials", func(t *testing.T) {
+		w := sendRequest(ctx, http.MethodPost, "/create", nil, "")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without credentials, found %v", w.Code)
+		}
+	})
+
+	t.Run("owner is the creating principal", func(t *testing.T) {
+		if decks[guid].Owner != "alice" {
+			t.Errorf("Expected alice to own the deck, found %v", decks[guid].Owner)
+		}
+	})
+
+	t.Run("player param cannot impersonate the owner", func(t *testing.T) {
+		url := fmt.Sprintf("/open/%s?player=alice", guid)
+		w := sendRequest(ctx, http.MethodGet, url, asBob, "")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to open alice's deck, found %v", w.Code)
+		}
+	})
+
+	t.Run("forbids drawing from someone else's deck", func(t *testing.T) {
+		url := fmt.Sprintf("/draw/%s", guid)
+		w := sendRequest(ctx, http.MethodPost, url, asBob, "")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to draw, found %v", w.Code)
+		}
+	})
+
+	t.Run("only owner may share", func(t *testing.T) {
+		url := fmt.Sprintf("/share/%s?with=bob", guid)
+		w := sendRequest(ctx, http.MethodPost, url, asBob, "")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to share, found %v", w.Code)
+		}
+	})
+
+	t.Run("shared deck is accessible", func(t *testing.T) {
+		url := fmt.Sprintf("/share/%s?with=bob,carol", guid)
+		w := sendRequest(ctx, http.MethodPost, url, asAlice, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected alice to share her deck, found %v", w.Code)
+		}
+
+		url = fmt.Sprintf("/draw/%s", guid)
+		w = sendRequest(ctx, http.MethodPost, url, asBob, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected bob to draw from a shared deck, found %v", w.Code)
+		}
+
+		url = fmt.Sprintf("/open/%s", guid)
+		w = sendRequest(ctx, http.MethodGet, url, map[string]string{"X-API-Key": "carols-key"}, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected carol to open a shared deck with her api key, found %v", w.Code)
+		}
+	})
+}
+
+func decodeCreatedDeck(t *testing.T, body string) CreatedDeck {
+	var deck CreatedDeck
+	if err := json.NewDecoder(strings.NewReader(body)).Decode(&deck); err != nil {
+		t.Fatalf("Failed to decode %v: %v", body, err)
+	}
+	return deck
+}
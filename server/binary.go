@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// encodes v with encode after going through JSON, so that binary formats
+// carry the fields JSON does under the same names. encode is handed maps,
+// slices, strings, json.Number, bools and nil
+func encodeTree(v any, encode func(*bytes.Buffer, any) error) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// integers when they are, floats otherwise
+func parseNumber(n json.Number) (int64, float64, bool) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, 0, true
+	}
+	f, _ := strconv.ParseFloat(string(n), 64)
+	return 0, f, false
+}
+
+// keys in the same order every time
+func sortedKeys(m map[string]any) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RFC 8949
+func encodeCBOR(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		i, f, isInt := parseNumber(v)
+		switch {
+		case !isInt:
+			buf.WriteByte(0xfb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		case i >= 0:
+			cborHead(buf, 0, uint64(i))
+		default:
+			cborHead(buf, 1, uint64(-1-i))
+		}
+	case string:
+		cborHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		cborHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		cborHead(buf, 5, uint64(len(v)))
+		for _, key := range sortedKeys(v) {
+			encodeCBOR(buf, key)
+			if err := encodeCBOR(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Cannot encode %T as CBOR", v)
+	}
+	return nil
+}
+
+// the major type and the shortest argument n fits in
+func cborHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// https://github.com/msgpack/msgpack/blob/master/spec.md
+func encodeMsgpack(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		i, f, isInt := parseNumber(v)
+		if !isInt {
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		} else {
+			msgpackInt(buf, i)
+		}
+	case string:
+		msgpackHead(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []any:
+		msgpackHead(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		msgpackHead(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range sortedKeys(v) {
+			encodeMsgpack(buf, key)
+			if err := encodeMsgpack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Cannot encode %T as MessagePack", v)
+	}
+	return nil
+}
+
+// the shortest of fix (below fixMax), 8 (unless there's none), 16 and 32
+// bit lengths
+func msgpackHead(buf *bytes.Buffer, n int, fix byte, fixMax int, head8 byte, head16 byte, head32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case head8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(head8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(head16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(head32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func msgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		binary.Write(buf, binary.BigEndian, int8(i))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
@@ -0,0 +1,927 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: cards.proto
+
+// the deck service works on the very same decks as the /v1/decks routes of
+// the HTTP API and answers the same way. Errors carry an ErrorInfo whose
+// reason is the code the HTTP API puts in its problems (i.e. not_enough_cards)
+
+package cardspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// every field is optional, as are the query parameters of POST /v1/decks
+type CreateDeckRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// codes of the cards to put in the deck, a full deck when empty
+	Cards    []string `protobuf:"bytes,1,rep,name=cards,proto3" json:"cards,omitempty"`
+	Shuffled bool     `protobuf:"varint,2,opt,name=shuffled,proto3" json:"shuffled,omitempty"`
+	// public, owner, facedown or hidden
+	Visibility string `protobuf:"bytes,3,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	// the type of game whose rules govern the deck
+	Rules string `protobuf:"bytes,4,opt,name=rules,proto3" json:"rules,omitempty"`
+	// how long the deck may sit idle before being evicted, as in 30m
+	Ttl           string `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDeckRequest) Reset() {
+	*x = CreateDeckRequest{}
+	mi := &file_cards_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDeckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDeckRequest) ProtoMessage() {}
+
+func (x *CreateDeckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDeckRequest.ProtoReflect.Descriptor instead.
+func (*CreateDeckRequest) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateDeckRequest) GetCards() []string {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+func (x *CreateDeckRequest) GetShuffled() bool {
+	if x != nil {
+		return x.Shuffled
+	}
+	return false
+}
+
+func (x *CreateDeckRequest) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *CreateDeckRequest) GetRules() string {
+	if x != nil {
+		return x.Rules
+	}
+	return ""
+}
+
+func (x *CreateDeckRequest) GetTtl() string {
+	if x != nil {
+		return x.Ttl
+	}
+	return ""
+}
+
+type CreatedDeck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeckId        string                 `protobuf:"bytes,1,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	Shuffled      bool                   `protobuf:"varint,2,opt,name=shuffled,proto3" json:"shuffled,omitempty"`
+	Remaining     int32                  `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	Version       int64                  `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatedDeck) Reset() {
+	*x = CreatedDeck{}
+	mi := &file_cards_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatedDeck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatedDeck) ProtoMessage() {}
+
+func (x *CreatedDeck) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatedDeck.ProtoReflect.Descriptor instead.
+func (*CreatedDeck) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreatedDeck) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+func (x *CreatedDeck) GetShuffled() bool {
+	if x != nil {
+		return x.Shuffled
+	}
+	return false
+}
+
+func (x *CreatedDeck) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *CreatedDeck) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type OpenDeckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeckId        string                 `protobuf:"bytes,1,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenDeckRequest) Reset() {
+	*x = OpenDeckRequest{}
+	mi := &file_cards_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenDeckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenDeckRequest) ProtoMessage() {}
+
+func (x *OpenDeckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenDeckRequest.ProtoReflect.Descriptor instead.
+func (*OpenDeckRequest) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OpenDeckRequest) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+// only what the caller is allowed to see makes it in
+type Deck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeckId        string                 `protobuf:"bytes,1,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	Shuffled      bool                   `protobuf:"varint,2,opt,name=shuffled,proto3" json:"shuffled,omitempty"`
+	Remaining     int32                  `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	Owner         string                 `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+	SharedWith    []string               `protobuf:"bytes,5,rep,name=shared_with,json=sharedWith,proto3" json:"shared_with,omitempty"`
+	Visibility    string                 `protobuf:"bytes,6,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	Cards         []*Card                `protobuf:"bytes,7,rep,name=cards,proto3" json:"cards,omitempty"`
+	Piles         map[string]*Pile       `protobuf:"bytes,8,rep,name=piles,proto3" json:"piles,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Version       int64                  `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Deck) Reset() {
+	*x = Deck{}
+	mi := &file_cards_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Deck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Deck) ProtoMessage() {}
+
+func (x *Deck) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Deck.ProtoReflect.Descriptor instead.
+func (*Deck) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Deck) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+func (x *Deck) GetShuffled() bool {
+	if x != nil {
+		return x.Shuffled
+	}
+	return false
+}
+
+func (x *Deck) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *Deck) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Deck) GetSharedWith() []string {
+	if x != nil {
+		return x.SharedWith
+	}
+	return nil
+}
+
+func (x *Deck) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *Deck) GetCards() []*Card {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+func (x *Deck) GetPiles() map[string]*Pile {
+	if x != nil {
+		return x.Piles
+	}
+	return nil
+}
+
+func (x *Deck) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Pile struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Owner      string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Visibility string                 `protobuf:"bytes,2,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	// only set when the cards themselves can't be seen
+	Count         *int32  `protobuf:"varint,3,opt,name=count,proto3,oneof" json:"count,omitempty"`
+	Cards         []*Card `protobuf:"bytes,4,rep,name=cards,proto3" json:"cards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pile) Reset() {
+	*x = Pile{}
+	mi := &file_cards_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pile) ProtoMessage() {}
+
+func (x *Pile) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pile.ProtoReflect.Descriptor instead.
+func (*Pile) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Pile) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Pile) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *Pile) GetCount() int32 {
+	if x != nil && x.Count != nil {
+		return *x.Count
+	}
+	return 0
+}
+
+func (x *Pile) GetCards() []*Card {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+type Card struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Suit          string                 `protobuf:"bytes,2,opt,name=suit,proto3" json:"suit,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Card) Reset() {
+	*x = Card{}
+	mi := &file_cards_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Card) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Card) ProtoMessage() {}
+
+func (x *Card) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Card.ProtoReflect.Descriptor instead.
+func (*Card) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Card) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Card) GetSuit() string {
+	if x != nil {
+		return x.Suit
+	}
+	return ""
+}
+
+func (x *Card) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+type DrawRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	DeckId string                 `protobuf:"bytes,1,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	// defaults to 1
+	Count int32 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// keeps the drawn cards on the table in a pile owned by the caller
+	Pile string `protobuf:"bytes,3,opt,name=pile,proto3" json:"pile,omitempty"`
+	// owner unless said otherwise
+	PileVisibility string `protobuf:"bytes,4,opt,name=pile_visibility,json=pileVisibility,proto3" json:"pile_visibility,omitempty"`
+	AllowPartial   bool   `protobuf:"varint,5,opt,name=allow_partial,json=allowPartial,proto3" json:"allow_partial,omitempty"`
+	// only draws when the deck is still at this version, like If-Match does
+	IfVersion     *int64 `protobuf:"varint,6,opt,name=if_version,json=ifVersion,proto3,oneof" json:"if_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrawRequest) Reset() {
+	*x = DrawRequest{}
+	mi := &file_cards_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrawRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrawRequest) ProtoMessage() {}
+
+func (x *DrawRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrawRequest.ProtoReflect.Descriptor instead.
+func (*DrawRequest) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DrawRequest) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+func (x *DrawRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *DrawRequest) GetPile() string {
+	if x != nil {
+		return x.Pile
+	}
+	return ""
+}
+
+func (x *DrawRequest) GetPileVisibility() string {
+	if x != nil {
+		return x.PileVisibility
+	}
+	return ""
+}
+
+func (x *DrawRequest) GetAllowPartial() bool {
+	if x != nil {
+		return x.AllowPartial
+	}
+	return false
+}
+
+func (x *DrawRequest) GetIfVersion() int64 {
+	if x != nil && x.IfVersion != nil {
+		return *x.IfVersion
+	}
+	return 0
+}
+
+type DrawResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cards         []*Card                `protobuf:"bytes,1,rep,name=cards,proto3" json:"cards,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrawResponse) Reset() {
+	*x = DrawResponse{}
+	mi := &file_cards_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrawResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrawResponse) ProtoMessage() {}
+
+func (x *DrawResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrawResponse.ProtoReflect.Descriptor instead.
+func (*DrawResponse) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DrawResponse) GetCards() []*Card {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+func (x *DrawResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type WatchDeckRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	DeckId string                 `protobuf:"bytes,1,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	// the seq of the last event seen, 0 for a fresh watch
+	Since         int64 `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDeckRequest) Reset() {
+	*x = WatchDeckRequest{}
+	mi := &file_cards_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDeckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDeckRequest) ProtoMessage() {}
+
+func (x *WatchDeckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDeckRequest.ProtoReflect.Descriptor instead.
+func (*WatchDeckRequest) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchDeckRequest) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+func (x *WatchDeckRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+type DeckEvent struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Seq       int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Type      string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	DeckId    string                 `protobuf:"bytes,3,opt,name=deck_id,json=deckId,proto3" json:"deck_id,omitempty"`
+	Actor     string                 `protobuf:"bytes,4,opt,name=actor,proto3" json:"actor,omitempty"`
+	Time      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Pile      string                 `protobuf:"bytes,6,opt,name=pile,proto3" json:"pile,omitempty"`
+	Count     int32                  `protobuf:"varint,7,opt,name=count,proto3" json:"count,omitempty"`
+	Remaining int32                  `protobuf:"varint,8,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// left out when the caller may not see them
+	Cards         []*Card  `protobuf:"bytes,9,rep,name=cards,proto3" json:"cards,omitempty"`
+	Players       []string `protobuf:"bytes,10,rep,name=players,proto3" json:"players,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeckEvent) Reset() {
+	*x = DeckEvent{}
+	mi := &file_cards_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeckEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeckEvent) ProtoMessage() {}
+
+func (x *DeckEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cards_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeckEvent.ProtoReflect.Descriptor instead.
+func (*DeckEvent) Descriptor() ([]byte, []int) {
+	return file_cards_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeckEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *DeckEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DeckEvent) GetDeckId() string {
+	if x != nil {
+		return x.DeckId
+	}
+	return ""
+}
+
+func (x *DeckEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *DeckEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *DeckEvent) GetPile() string {
+	if x != nil {
+		return x.Pile
+	}
+	return ""
+}
+
+func (x *DeckEvent) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *DeckEvent) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *DeckEvent) GetCards() []*Card {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+func (x *DeckEvent) GetPlayers() []string {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
+var File_cards_proto protoreflect.FileDescriptor
+
+const file_cards_proto_rawDesc = "" +
+	"\n" +
+	"\vcards.proto\x12\bcards.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8d\x01\n" +
+	"\x11CreateDeckRequest\x12\x14\n" +
+	"\x05cards\x18\x01 \x03(\tR\x05cards\x12\x1a\n" +
+	"\bshuffled\x18\x02 \x01(\bR\bshuffled\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x03 \x01(\tR\n" +
+	"visibility\x12\x14\n" +
+	"\x05rules\x18\x04 \x01(\tR\x05rules\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\tR\x03ttl\"z\n" +
+	"\vCreatedDeck\x12\x17\n" +
+	"\adeck_id\x18\x01 \x01(\tR\x06deckId\x12\x1a\n" +
+	"\bshuffled\x18\x02 \x01(\bR\bshuffled\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\x05R\tremaining\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"*\n" +
+	"\x0fOpenDeckRequest\x12\x17\n" +
+	"\adeck_id\x18\x01 \x01(\tR\x06deckId\"\xeb\x02\n" +
+	"\x04Deck\x12\x17\n" +
+	"\adeck_id\x18\x01 \x01(\tR\x06deckId\x12\x1a\n" +
+	"\bshuffled\x18\x02 \x01(\bR\bshuffled\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\x05R\tremaining\x12\x14\n" +
+	"\x05owner\x18\x04 \x01(\tR\x05owner\x12\x1f\n" +
+	"\vshared_with\x18\x05 \x03(\tR\n" +
+	"sharedWith\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x06 \x01(\tR\n" +
+	"visibility\x12$\n" +
+	"\x05cards\x18\a \x03(\v2\x0e.cards.v1.CardR\x05cards\x12/\n" +
+	"\x05piles\x18\b \x03(\v2\x19.cards.v1.Deck.PilesEntryR\x05piles\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\x1aH\n" +
+	"\n" +
+	"PilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.cards.v1.PileR\x05value:\x028\x01\"\x87\x01\n" +
+	"\x04Pile\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x02 \x01(\tR\n" +
+	"visibility\x12\x19\n" +
+	"\x05count\x18\x03 \x01(\x05H\x00R\x05count\x88\x01\x01\x12$\n" +
+	"\x05cards\x18\x04 \x03(\v2\x0e.cards.v1.CardR\x05cardsB\b\n" +
+	"\x06_count\"D\n" +
+	"\x04Card\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x12\n" +
+	"\x04suit\x18\x02 \x01(\tR\x04suit\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\"\xd1\x01\n" +
+	"\vDrawRequest\x12\x17\n" +
+	"\adeck_id\x18\x01 \x01(\tR\x06deckId\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x12\x12\n" +
+	"\x04pile\x18\x03 \x01(\tR\x04pile\x12'\n" +
+	"\x0fpile_visibility\x18\x04 \x01(\tR\x0epileVisibility\x12#\n" +
+	"\rallow_partial\x18\x05 \x01(\bR\fallowPartial\x12\"\n" +
+	"\n" +
+	"if_version\x18\x06 \x01(\x03H\x00R\tifVersion\x88\x01\x01B\r\n" +
+	"\v_if_version\"N\n" +
+	"\fDrawResponse\x12$\n" +
+	"\x05cards\x18\x01 \x03(\v2\x0e.cards.v1.CardR\x05cards\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"A\n" +
+	"\x10WatchDeckRequest\x12\x17\n" +
+	"\adeck_id\x18\x01 \x01(\tR\x06deckId\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x03R\x05since\"\x98\x02\n" +
+	"\tDeckEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x17\n" +
+	"\adeck_id\x18\x03 \x01(\tR\x06deckId\x12\x14\n" +
+	"\x05actor\x18\x04 \x01(\tR\x05actor\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04pile\x18\x06 \x01(\tR\x04pile\x12\x14\n" +
+	"\x05count\x18\a \x01(\x05R\x05count\x12\x1c\n" +
+	"\tremaining\x18\b \x01(\x05R\tremaining\x12$\n" +
+	"\x05cards\x18\t \x03(\v2\x0e.cards.v1.CardR\x05cards\x12\x18\n" +
+	"\aplayers\x18\n" +
+	" \x03(\tR\aplayers2\xfd\x01\n" +
+	"\vDeckService\x12@\n" +
+	"\n" +
+	"CreateDeck\x12\x1b.cards.v1.CreateDeckRequest\x1a\x15.cards.v1.CreatedDeck\x125\n" +
+	"\bOpenDeck\x12\x19.cards.v1.OpenDeckRequest\x1a\x0e.cards.v1.Deck\x125\n" +
+	"\x04Draw\x12\x15.cards.v1.DrawRequest\x1a\x16.cards.v1.DrawResponse\x12>\n" +
+	"\tWatchDeck\x12\x1a.cards.v1.WatchDeckRequest\x1a\x13.cards.v1.DeckEvent0\x01B\x1cZ\x1aexample.com/server/cardspbb\x06proto3"
+
+var (
+	file_cards_proto_rawDescOnce sync.Once
+	file_cards_proto_rawDescData []byte
+)
+
+func file_cards_proto_rawDescGZIP() []byte {
+	file_cards_proto_rawDescOnce.Do(func() {
+		file_cards_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_cards_proto_rawDesc), len(file_cards_proto_rawDesc)))
+	})
+	return file_cards_proto_rawDescData
+}
+
+var file_cards_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_cards_proto_goTypes = []any{
+	(*CreateDeckRequest)(nil),     // 0: cards.v1.CreateDeckRequest
+	(*CreatedDeck)(nil),           // 1: cards.v1.CreatedDeck
+	(*OpenDeckRequest)(nil),       // 2: cards.v1.OpenDeckRequest
+	(*Deck)(nil),                  // 3: cards.v1.Deck
+	(*Pile)(nil),                  // 4: cards.v1.Pile
+	(*Card)(nil),                  // 5: cards.v1.Card
+	(*DrawRequest)(nil),           // 6: cards.v1.DrawRequest
+	(*DrawResponse)(nil),          // 7: cards.v1.DrawResponse
+	(*WatchDeckRequest)(nil),      // 8: cards.v1.WatchDeckRequest
+	(*DeckEvent)(nil),             // 9: cards.v1.DeckEvent
+	nil,                           // 10: cards.v1.Deck.PilesEntry
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_cards_proto_depIdxs = []int32{
+	5,  // 0: cards.v1.Deck.cards:type_name -> cards.v1.Card
+	10, // 1: cards.v1.Deck.piles:type_name -> cards.v1.Deck.PilesEntry
+	5,  // 2: cards.v1.Pile.cards:type_name -> cards.v1.Card
+	5,  // 3: cards.v1.DrawResponse.cards:type_name -> cards.v1.Card
+	11, // 4: cards.v1.DeckEvent.time:type_name -> google.protobuf.Timestamp
+	5,  // 5: cards.v1.DeckEvent.cards:type_name -> cards.v1.Card
+	4,  // 6: cards.v1.Deck.PilesEntry.value:type_name -> cards.v1.Pile
+	0,  // 7: cards.v1.DeckService.CreateDeck:input_type -> cards.v1.CreateDeckRequest
+	2,  // 8: cards.v1.DeckService.OpenDeck:input_type -> cards.v1.OpenDeckRequest
+	6,  // 9: cards.v1.DeckService.Draw:input_type -> cards.v1.DrawRequest
+	8,  // 10: cards.v1.DeckService.WatchDeck:input_type -> cards.v1.WatchDeckRequest
+	1,  // 11: cards.v1.DeckService.CreateDeck:output_type -> cards.v1.CreatedDeck
+	3,  // 12: cards.v1.DeckService.OpenDeck:output_type -> cards.v1.Deck
+	7,  // 13: cards.v1.DeckService.Draw:output_type -> cards.v1.DrawResponse
+	9,  // 14: cards.v1.DeckService.WatchDeck:output_type -> cards.v1.DeckEvent
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_cards_proto_init() }
+func file_cards_proto_init() {
+	if File_cards_proto != nil {
+		return
+	}
+	file_cards_proto_msgTypes[4].OneofWrappers = []any{}
+	file_cards_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cards_proto_rawDesc), len(file_cards_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cards_proto_goTypes,
+		DependencyIndexes: file_cards_proto_depIdxs,
+		MessageInfos:      file_cards_proto_msgTypes,
+	}.Build()
+	File_cards_proto = out.File
+	file_cards_proto_goTypes = nil
+	file_cards_proto_depIdxs = nil
+}
@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: cards.proto
+
+// the deck service works on the very same decks as the /v1/decks routes of
+// the HTTP API and answers the same way. Errors carry an ErrorInfo whose
+// reason is the code the HTTP API puts in its problems (i.e. not_enough_cards)
+
+package cardspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DeckService_CreateDeck_FullMethodName = "/cards.v1.DeckService/CreateDeck"
+	DeckService_OpenDeck_FullMethodName   = "/cards.v1.DeckService/OpenDeck"
+	DeckService_Draw_FullMethodName       = "/cards.v1.DeckService/Draw"
+	DeckService_WatchDeck_FullMethodName  = "/cards.v1.DeckService/WatchDeck"
+)
+
+// DeckServiceClient is the client API for DeckService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DeckServiceClient interface {
+	CreateDeck(ctx context.Context, in *CreateDeckRequest, opts ...grpc.CallOption) (*CreatedDeck, error)
+	OpenDeck(ctx context.Context, in *OpenDeckRequest, opts ...grpc.CallOption) (*Deck, error)
+	// fails with FAILED_PRECONDITION when fewer than count cards are left,
+	// unless allow_partial settles for whatever is left
+	Draw(ctx context.Context, in *DrawRequest, opts ...grpc.CallOption) (*DrawResponse, error)
+	// streams what happens to a deck until the client hangs up. A stream that
+	// can't keep up is ended with UNAVAILABLE and should be resumed with since
+	WatchDeck(ctx context.Context, in *WatchDeckRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DeckEvent], error)
+}
+
+type deckServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDeckServiceClient(cc grpc.ClientConnInterface) DeckServiceClient {
+	return &deckServiceClient{cc}
+}
+
+func (c *deckServiceClient) CreateDeck(ctx context.Context, in *CreateDeckRequest, opts ...grpc.CallOption) (*CreatedDeck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreatedDeck)
+	err := c.cc.Invoke(ctx, DeckService_CreateDeck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deckServiceClient) OpenDeck(ctx context.Context, in *OpenDeckRequest, opts ...grpc.CallOption) (*Deck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Deck)
+	err := c.cc.Invoke(ctx, DeckService_OpenDeck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deckServiceClient) Draw(ctx context.Context, in *DrawRequest, opts ...grpc.CallOption) (*DrawResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrawResponse)
+	err := c.cc.Invoke(ctx, DeckService_Draw_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deckServiceClient) WatchDeck(ctx context.Context, in *WatchDeckRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DeckEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DeckService_ServiceDesc.Streams[0], DeckService_WatchDeck_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchDeckRequest, DeckEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeckService_WatchDeckClient = grpc.ServerStreamingClient[DeckEvent]
+
+// DeckServiceServer is the server API for DeckService service.
+// All implementations must embed UnimplementedDeckServiceServer
+// for forward compatibility.
+type DeckServiceServer interface {
+	CreateDeck(context.Context, *CreateDeckRequest) (*CreatedDeck, error)
+	OpenDeck(context.Context, *OpenDeckRequest) (*Deck, error)
+	// fails with FAILED_PRECONDITION when fewer than count cards are left,
+	// unless allow_partial settles for whatever is left
+	Draw(context.Context, *DrawRequest) (*DrawResponse, error)
+	// streams what happens to a deck until the client hangs up. A stream that
+	// can't keep up is ended with UNAVAILABLE and should be resumed with since
+	WatchDeck(*WatchDeckRequest, grpc.ServerStreamingServer[DeckEvent]) error
+	mustEmbedUnimplementedDeckServiceServer()
+}
+
+// UnimplementedDeckServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDeckServiceServer struct{}
+
+func (UnimplementedDeckServiceServer) CreateDeck(context.Context, *CreateDeckRequest) (*CreatedDeck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDeck not implemented")
+}
+func (UnimplementedDeckServiceServer) OpenDeck(context.Context, *OpenDeckRequest) (*Deck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OpenDeck not implemented")
+}
+func (UnimplementedDeckServiceServer) Draw(context.Context, *DrawRequest) (*DrawResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Draw not implemented")
+}
+func (UnimplementedDeckServiceServer) WatchDeck(*WatchDeckRequest, grpc.ServerStreamingServer[DeckEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDeck not implemented")
+}
+func (UnimplementedDeckServiceServer) mustEmbedUnimplementedDeckServiceServer() {}
+func (UnimplementedDeckServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeDeckServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DeckServiceServer will
+// result in compilation errors.
+type UnsafeDeckServiceServer interface {
+	mustEmbedUnimplementedDeckServiceServer()
+}
+
+func RegisterDeckServiceServer(s grpc.ServiceRegistrar, srv DeckServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDeckServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DeckService_ServiceDesc, srv)
+}
+
+func _DeckService_CreateDeck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDeckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeckServiceServer).CreateDeck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeckService_CreateDeck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeckServiceServer).CreateDeck(ctx, req.(*CreateDeckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeckService_OpenDeck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OpenDeckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeckServiceServer).OpenDeck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeckService_OpenDeck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeckServiceServer).OpenDeck(ctx, req.(*OpenDeckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeckService_Draw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeckServiceServer).Draw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeckService_Draw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeckServiceServer).Draw(ctx, req.(*DrawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeckService_WatchDeck_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDeckRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeckServiceServer).WatchDeck(m, &grpc.GenericServerStream[WatchDeckRequest, DeckEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeckService_WatchDeckServer = grpc.ServerStreamingServer[DeckEvent]
+
+// DeckService_ServiceDesc is the grpc.ServiceDesc for DeckService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DeckService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cards.v1.DeckService",
+	HandlerType: (*DeckServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateDeck",
+			Handler:    _DeckService_CreateDeck_Handler,
+		},
+		{
+			MethodName: "OpenDeck",
+			Handler:    _DeckService_OpenDeck_Handler,
+		},
+		{
+			MethodName: "Draw",
+			Handler:    _DeckService_Draw_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDeck",
+			Handler:       _DeckService_WatchDeck_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cards.proto",
+}
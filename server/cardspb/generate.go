@@ -0,0 +1,5 @@
+// Package cardspb holds the protobuf messages and gRPC service of the deck
+// service, generated from cards.proto
+package cardspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cards.proto
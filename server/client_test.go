@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example.com/client"
+	"example.com/deck"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// the client lives in its own module so this is where it meets the real
+// handlers
+func TestClient(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	server := httptest.NewServer(ctx.Routes())
+	defer server.Close()
+
+	background := context.Background()
+	alice := client.New(server.URL, client.WithPlayer("alice"))
+	created, err := alice.CreateDeck(background, client.CreateOptions{Cards: []string{"AS", "KD", "10H"}})
+	if err != nil || created.Remaining != 3 {
+		t.Fatalf("Expected a deck of 3 cards to be created, found %v (%v)", created, err)
+	}
+
+	t.Run("opens and draws", func(t *testing.T) {
+		opened, err := alice.OpenDeck(background, created.ID)
+		if err != nil || opened.Remaining != 3 || opened.Owner != "alice" {
+			t.Fatalf("Expected alice's deck to be opened, found %v (%v)", opened, err)
+		}
+
+		cards, err := alice.Draw(background, created.ID, client.DrawOptions{IfVersion: &opened.Version})
+		if err != nil || len(cards) != 1 || cards[0].Code != "10H" {
+			t.Errorf("Expected 10H to be drawn, found %v (%v)", cards, err)
+		}
+
+		_, err = alice.Draw(background, created.ID, client.DrawOptions{IfVersion: &opened.Version})
+		if !errors.Is(err, client.ErrVersionMismatch) {
+			t.Errorf("Expected ErrVersionMismatch for a stale version, found %v", err)
+		}
+	})
+
+	t.Run("deals", func(t *testing.T) {
+		other, _ := alice.CreateDeck(background, client.CreateOptions{Cards: []string{"AS", "KD", "QH", "JC"}})
+		dealt, err := alice.Deal(background, other.ID, client.DealOptions{Players: []string{"alice", "bob"}, Count: 2})
+		if err != nil || dealt.Remaining != 0 || len(dealt.Piles["alice"].Cards) != 2 || dealt.Piles["bob"].Cards != nil {
+			t.Errorf("Expected alice to see her own hand only, found %v (%v)", dealt, err)
+		}
+	})
+
+	t.Run("mirrors server errors", func(t *testing.T) {
+		_, err := alice.Draw(background, created.ID, client.DrawOptions{Count: 5})
+		var e *client.Error
+		if !errors.Is(err, client.ErrNotEnoughCards) || !errors.As(err, &e) || *e.Remaining != 2 {
+			t.Errorf("Expected ErrNotEnoughCards with 2 remaining, found %v", err)
+		}
+
+		if _, err := alice.OpenDeck(background, uuid.New()); !errors.Is(err, client.ErrDeckNotFound) {
+			t.Errorf("Expected ErrDeckNotFound, found %v", err)
+		}
+
+		_, err = alice.CreateDeck(background, client.CreateOptions{Cards: []string{"AS", "ZZ"}})
+		if !errors.Is(err, client.ErrInvalidCard) || !errors.As(err, &e) || e.Input != "ZZ" {
+			t.Errorf("Expected ErrInvalidCard for ZZ, found %v", err)
+		}
+
+		bob := client.New(server.URL, client.WithPlayer("bob"))
+		if _, err := bob.OpenDeck(background, created.ID); !errors.Is(err, client.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for bob, found %v", err)
+		}
+	})
+
+	t.Run("plays games", func(t *testing.T) {
+		view, err := alice.CreateGame(background, "highcard", "alice", "bob")
+		if err != nil || view.Turn != "alice" || len(view.Legal) != 5 {
+			t.Fatalf("Expected alice to start a game, found %v (%v)", view, err)
+		}
+
+		view, err = alice.Act(background, "highcard", view.ID, view.Legal[0])
+		if err != nil || view.Turn != "bob" || len(view.Piles["table"].Cards) != 1 {
+			t.Fatalf("Expected alice's card on the table, found %v (%v)", view, err)
+		}
+
+		bobs, err := alice.As("bob").ViewGame(background, "highcard", view.ID)
+		if err != nil || bobs.Player != "bob" || len(bobs.Legal) != 5 || bobs.Piles["hand:alice"].Cards != nil {
+			t.Errorf("Expected bob's view without alice's cards, found %v (%v)", bobs, err)
+		}
+
+		card := view.Piles["hand:alice"].Cards[0].Code
+		_, err = alice.Act(background, "highcard", view.ID, client.Action{Type: "play", Cards: []string{card}})
+		var e *client.Error
+		if !errors.As(err, &e) || e.Status != http.StatusConflict {
+			t.Errorf("Expected a 409 playing out of turn, found %v", err)
+		}
+	})
+}
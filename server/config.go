@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is everything the server is told at startup. Settings start from
+// their defaults and are then overridden by the config file (--config or
+// CARDS_CONFIG), then by CARDS_* environment variables and finally by flags.
+// Every setting goes by the same name everywhere: --grpc-addr is
+// CARDS_GRPC_ADDR and "grpc-addr" in the config file. Secrets are the
+// exception and can't be flags, which anyone on the machine can read
+type Config struct {
+	Addr     string
+	GRPCAddr string
+	// how long a client may take to send a request and to read the response
+	// (streams of events excepted), and keep an idle connection open
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// how long requests in flight are given to finish on shutdown
+	ShutdownTimeout time.Duration
+	// both or neither, serving HTTPS and gRPC over TLS when given
+	TLSCert string
+	TLSKey  string
+	// * allows any origin
+	CORSOrigins     []string
+	CORSCredentials bool
+	CORSMaxAge      time.Duration
+	// memory or file, which keeps decks as snapshots in StoragePath
+	Storage          string
+	StoragePath      string
+	DefaultTTL       time.Duration
+	MaxDecksPerOwner int
+	// requests per second a single client may make, 0 for no limit, unless
+	// the operation (as in Create) has its own in RateLimits
+	RateLimit   float64
+	RateBurst   int
+	RateLimits  map[string]float64
+	MaxDeckSize int
+	// bytes
+	MaxBodySize int64
+	LogLevel    string
+	// json or text, written to stderr
+	LogFormat string
+	// exports a span per request to stdout
+	Trace             bool
+	IdempotencyWindow time.Duration
+	AuthSecret        string
+	APIKeys           map[string]string
+}
+
+func DefaultConfig() Config {
+	retention := DefaultRetention()
+	limits := DefaultLimits()
+	return Config{
+		Addr:              ":8000",
+		GRPCAddr:          ":9000",
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		CORSOrigins:       DefaultCORS().Origins,
+		CORSMaxAge:        DefaultCORS().MaxAge,
+		Storage:           "memory",
+		DefaultTTL:        retention.DefaultTTL,
+		MaxDecksPerOwner:  retention.MaxDecksPerOwner,
+		RateLimit:         limits.Rate,
+		RateBurst:         limits.Burst,
+		RateLimits:        map[string]float64{},
+		MaxDeckSize:       limits.MaxDeckSize,
+		MaxBodySize:       limits.MaxBodySize,
+		LogLevel:          "info",
+		LogFormat:         "json",
+		IdempotencyWindow: 24 * time.Hour,
+		APIKeys:           map[string]string{},
+	}
+}
+
+func (c *Config) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "where the HTTP API listens")
+	fs.StringVar(&c.GRPCAddr, "grpc-addr", c.GRPCAddr, "where the gRPC deck service listens")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "how long a client may take to send a request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "how long a client may take to read a response, streams of events excepted")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "how long an idle connection is kept open")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "how long requests in flight are given to finish on shutdown")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "certificate file to serve TLS with")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "key file of the certificate")
+	fs.Var((*listValue)(&c.CORSOrigins), "cors-origins", "origins browsers may call the API from, as in https://a.com,https://b.com or *")
+	fs.BoolVar(&c.CORSCredentials, "cors-credentials", c.CORSCredentials, "lets browsers send cookies from the allowed origins")
+	fs.DurationVar(&c.CORSMaxAge, "cors-max-age", c.CORSMaxAge, "how long browsers may cache the answer to a preflight")
+	fs.StringVar(&c.Storage, "storage", c.Storage, "where decks are kept: memory or file")
+	fs.StringVar(&c.StoragePath, "storage-path", c.StoragePath, "the file decks are kept in with the file storage")
+	fs.DurationVar(&c.DefaultTTL, "default-ttl", c.DefaultTTL, "how long a deck may sit idle unless created with a ttl")
+	fs.IntVar(&c.MaxDecksPerOwner, "max-decks-per-owner", c.MaxDecksPerOwner, "how many decks a single owner may keep around")
+	fs.Float64Var(&c.RateLimit, "rate-limit", c.RateLimit, "requests per second a single client may make, 0 for no limit")
+	fs.IntVar(&c.RateBurst, "rate-burst", c.RateBurst, "requests a client may make at once above the rate limit")
+	fs.Var((*rateLimitsValue)(&c.RateLimits), "rate-limits", "requests per second per operation instead of rate-limit, as in Create=0.5,Draw=10")
+	fs.IntVar(&c.MaxDeckSize, "max-deck-size", c.MaxDeckSize, "how many cards a custom or imported deck may have")
+	fs.Int64Var(&c.MaxBodySize, "max-body-size", c.MaxBodySize, "how many bytes a request body may have")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "json or text")
+	fs.BoolVar(&c.Trace, "trace", c.Trace, "writes a span per request to stdout, as the OpenTelemetry stdout exporter would")
+	fs.DurationVar(&c.IdempotencyWindow, "idempotency-window", c.IdempotencyWindow, "how long responses are kept for retries with an Idempotency-Key")
+}
+
+// only ever set from the environment or the config file
+func (c *Config) registerSecrets(fs *flag.FlagSet) {
+	fs.StringVar(&c.AuthSecret, "auth-secret", c.AuthSecret, "enables bearer tokens signed with this secret")
+	fs.Var((*apiKeysValue)(&c.APIKeys), "api-keys", "enables api keys, as in key1=alice,key2=bob")
+}
+
+// getenv is os.Getenv outside of tests. --help is flag.ErrHelp
+func LoadConfig(args []string, getenv func(string) string, output io.Writer) (Config, error) {
+	c := DefaultConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(output)
+	path := fs.String("config", getenv("CARDS_CONFIG"), "a JSON config file, as in {\"addr\": \":8080\"}")
+	c.register(fs)
+	secrets := flag.NewFlagSet("secrets", flag.ContinueOnError)
+	c.registerSecrets(secrets)
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	if fs.NArg() > 0 {
+		msg := fmt.Sprintf("Unexpected argument %q", fs.Arg(0))
+		return c, errors.New(msg)
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	settings := map[string]string{}
+	if *path != "" {
+		var err error
+		if settings, err = readConfigFile(*path); err != nil {
+			return c, err
+		}
+	}
+	fromEnv := func(f *flag.Flag) {
+		if value := getenv(envName(f.Name)); value != "" {
+			settings[f.Name] = value
+		}
+	}
+	fs.VisitAll(fromEnv)
+	secrets.VisitAll(fromEnv)
+
+	names := []string{}
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || given[name] {
+			continue
+		}
+		set := fs
+		if secrets.Lookup(name) != nil {
+			set = secrets
+		}
+		if set.Lookup(name) == nil {
+			msg := fmt.Sprintf("Unknown setting %q in %v", name, *path)
+			return c, errors.New(msg)
+		}
+		err := set.Set(name, settings[name])
+		if err != nil && set == secrets {
+			return c, fmt.Errorf("Invalid %v: %w", name, err)
+		}
+		if err != nil {
+			return c, fmt.Errorf("Invalid %v %q: %w", name, settings[name], err)
+		}
+	}
+	return c, c.Validate()
+}
+
+// as in CARDS_GRPC_ADDR for grpc-addr
+func envName(setting string) string {
+	return "CARDS_" + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// values are taken as they would be on the command line, lists may be
+// written as arrays and api keys as an object
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as written, 2097152 would otherwise be 2.097152e+06
+	var raw map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("Failed to read %v: %w", path, err)
+	}
+
+	settings := map[string]string{}
+	for name, value := range raw {
+		switch v := value.(type) {
+		case []any:
+			items := []string{}
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			settings[name] = strings.Join(items, ",")
+		case map[string]any:
+			pairs := []string{}
+			for key, item := range v {
+				pairs = append(pairs, fmt.Sprintf("%v=%v", key, item))
+			}
+			sort.Strings(pairs)
+			settings[name] = strings.Join(pairs, ",")
+		default:
+			settings[name] = fmt.Sprint(v)
+		}
+	}
+	return settings, nil
+}
+
+// every problem at once so they can be fixed in one go
+func (c *Config) Validate() error {
+	problems := []error{}
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		problem("Invalid addr %q: %w", c.Addr, err)
+	}
+	if _, _, err := net.SplitHostPort(c.GRPCAddr); err != nil {
+		problem("Invalid grpc-addr %q: %w", c.GRPCAddr, err)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read-timeout", c.ReadTimeout},
+		{"write-timeout", c.WriteTimeout},
+		{"idle-timeout", c.IdleTimeout},
+		{"shutdown-timeout", c.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			problem("%v must be positive, found %v", timeout.name, timeout.value)
+		}
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		problem("tls-cert and tls-key go together")
+	}
+	for _, file := range []string{c.TLSCert, c.TLSKey} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			problem("Cannot read %v: %w", file, err)
+		}
+	}
+
+	if len(c.CORSOrigins) == 0 {
+		problem("cors-origins needs at least one origin, or *")
+	}
+	for _, origin := range c.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			problem("Invalid origin %q, as in https://example.com", origin)
+		}
+	}
+	if c.CORSCredentials && slices.Contains(c.CORSOrigins, "*") {
+		problem("cors-credentials needs cors-origins to name the origins, not *")
+	}
+	if c.CORSMaxAge < 0 {
+		problem("cors-max-age cannot be negative, found %v", c.CORSMaxAge)
+	}
+
+	switch c.Storage {
+	case "memory":
+	case "file":
+		if c.StoragePath == "" {
+			problem("The file storage needs a storage-path")
+		}
+	default:
+		problem("Unknown storage %q, either memory or file", c.Storage)
+	}
+
+	maxTTL := DefaultRetention().MaxTTL
+	if c.DefaultTTL <= 0 || c.DefaultTTL > maxTTL {
+		problem("default-ttl must be positive and at most %v, found %v", maxTTL, c.DefaultTTL)
+	}
+	if c.MaxDecksPerOwner < 1 {
+		problem("max-decks-per-owner must be at least 1, found %d", c.MaxDecksPerOwner)
+	}
+	if c.RateLimit < 0 {
+		problem("rate-limit cannot be negative, found %v", c.RateLimit)
+	}
+	operations := []string{}
+	for operation, rate := range c.RateLimits {
+		operations = append(operations, operation)
+		if rate < 0 {
+			problem("The rate limit of %v cannot be negative, found %v", operation, rate)
+		}
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		if !isOperation(operation) {
+			problem("Unknown operation %q in rate-limits, as in Create or Draw", operation)
+		}
+	}
+	if (c.RateLimit > 0 || len(c.RateLimits) > 0) && c.RateBurst < 1 {
+		problem("rate-burst must be at least 1, found %d", c.RateBurst)
+	}
+	if c.MaxDeckSize < 1 {
+		problem("max-deck-size must be at least 1, found %d", c.MaxDeckSize)
+	}
+	if c.MaxBodySize < 1 {
+		problem("max-body-size must be at least 1, found %d", c.MaxBodySize)
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		problem("Unknown log-level %q, one of debug, info, warn or error", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		problem("Unknown log-format %q, either json or text", c.LogFormat)
+	}
+	if c.IdempotencyWindow <= 0 {
+		problem("idempotency-window must be positive, found %v", c.IdempotencyWindow)
+	}
+	return errors.Join(problems...)
+}
+
+// the effective config, secrets left out
+func (c *Config) Print(w io.Writer) {
+	secret := "(none)"
+	if c.AuthSecret != "" {
+		secret = "(set)"
+	}
+
+	settings := [][2]any{
+		{"addr", c.Addr},
+		{"grpc-addr", c.GRPCAddr},
+		{"read-timeout", c.ReadTimeout},
+		{"write-timeout", c.WriteTimeout},
+		{"idle-timeout", c.IdleTimeout},
+		{"shutdown-timeout", c.ShutdownTimeout},
+		{"tls-cert", c.TLSCert},
+		{"tls-key", c.TLSKey},
+		{"cors-origins", strings.Join(c.CORSOrigins, ",")},
+		{"cors-credentials", c.CORSCredentials},
+		{"cors-max-age", c.CORSMaxAge},
+		{"storage", c.Storage},
+		{"storage-path", c.StoragePath},
+		{"default-ttl", c.DefaultTTL},
+		{"max-decks-per-owner", c.MaxDecksPerOwner},
+		{"rate-limit", c.RateLimit},
+		{"rate-burst", c.RateBurst},
+		{"rate-limits", (*rateLimitsValue)(&c.RateLimits)},
+		{"max-deck-size", c.MaxDeckSize},
+		{"max-body-size", c.MaxBodySize},
+		{"log-level", c.LogLevel},
+		{"log-format", c.LogFormat},
+		{"trace", c.Trace},
+		{"idempotency-window", c.IdempotencyWindow},
+		{"auth-secret", secret},
+		{"api-keys", fmt.Sprintf("(%d)", len(c.APIKeys))},
+	}
+	for _, setting := range settings {
+		fmt.Fprintf(w, "%-20v %v\n", setting[0], setting[1])
+	}
+}
+
+// applies what the handlers care about
+func (c *Config) apply(ctx *HandlerContext) {
+	ctx.retention.DefaultTTL = c.DefaultTTL
+	ctx.retention.MaxDecksPerOwner = c.MaxDecksPerOwner
+	ctx.idempotencyWindow = c.IdempotencyWindow
+	ctx.crossOrigin = CORS{c.CORSOrigins, c.CORSCredentials, c.CORSMaxAge}
+	ctx.limits = Limits{c.RateLimit, c.RateBurst, c.RateLimits, c.MaxDeckSize, c.MaxBodySize}
+	if c.AuthSecret != "" || len(c.APIKeys) > 0 {
+		ctx.auth = NewAuthenticator([]byte(c.AuthSecret), c.APIKeys)
+	}
+	if c.Storage == "file" {
+		ctx.store = &fileStore{c.StoragePath}
+	}
+}
+
+// a,b,c on the command line. Setting it again replaces the list
+type listValue []string
+
+func (l *listValue) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// key1=alice,key2=bob on the command line
+type apiKeysValue map[string]string
+
+func (a *apiKeysValue) String() string {
+	return fmt.Sprintf("(%d)", len(*a))
+}
+
+func (a *apiKeysValue) Set(value string) error {
+	keys := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		key, principal, ok := strings.Cut(pair, "=")
+		if !ok || key == "" || principal == "" {
+			// the pair is left out as it may well be a key
+			return errors.New("Expected key=principal in every pair")
+		}
+		keys[key] = principal
+	}
+	*a = keys
+	return nil
+}
+
+// Create=0.5,Draw=10 on the command line
+type rateLimitsValue map[string]float64
+
+func (l *rateLimitsValue) String() string {
+	pairs := []string{}
+	for operation, rate := range *l {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", operation, rate))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l *rateLimitsValue) Set(value string) error {
+	rates := map[string]float64{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		operation, param, ok := strings.Cut(pair, "=")
+		rate, err := strconv.ParseFloat(param, 64)
+		if !ok || operation == "" || err != nil {
+			msg := fmt.Sprintf("Expected operation=requests per second, found %q", pair)
+			return errors.New(msg)
+		}
+		rates[operation] = rate
+	}
+	*l = rates
+	return nil
+}
+
+// whether name is one of the handlers, which is what operations are named
+// after
+func isOperation(name string) bool {
+	method, ok := reflect.TypeFor[*HandlerContext]().MethodByName(name)
+	return ok && method.Type == reflect.TypeFor[func(*HandlerContext, http.ResponseWriter, *http.Request)]()
+}
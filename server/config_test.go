@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"example.com/deck"
+	"flag"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	t.Run("defaults to what the server always did", func(t *testing.T) {
+		c, err := LoadConfig([]string{}, env(nil), io.Discard)
+		if err != nil || c.Addr != ":8000" || c.GRPCAddr != ":9000" || c.CORSOrigins[0] != "*" || c.Storage != "memory" {
+			t.Errorf("Expected the defaults, found %+v (%v)", c, err)
+		}
+	})
+
+	t.Run("overrides the file with env and env with flags", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cards.json")
+		os.WriteFile(path, []byte(`{
+			"addr": ":7000",
+			"grpc-addr": ":7001",
+			"cors-origins": ["https://a.com", "https://b.com"],
+			"default-ttl": "1h",
+			"max-body-size": 2097152,
+			"api-keys": {"k1": "alice", "k2": "bob"},
+			"rate-limits": {"Create": 0.5, "Draw": 10}
+		}`), 0o644)
+
+		vars := map[string]string{"CARDS_CONFIG": path, "CARDS_GRPC_ADDR": ":7002", "CARDS_ADDR": ":7003"}
+		c, err := LoadConfig([]string{"--addr", ":7004"}, env(vars), io.Discard)
+		if err != nil {
+			t.Fatalf("Expected the config to load, found %v", err)
+		}
+
+		if c.Addr != ":7004" || c.GRPCAddr != ":7002" || c.DefaultTTL != time.Hour || c.MaxBodySize != 2097152 {
+			t.Errorf("Expected flags over env over file, found %+v", c)
+		}
+		if strings.Join(c.CORSOrigins, " ") != "https://a.com https://b.com" || c.APIKeys["k2"] != "bob" || c.RateLimits["Create"] != 0.5 {
+			t.Errorf("Expected lists, api keys and rate limits from the file, found %v %v %v", c.CORSOrigins, c.APIKeys, c.RateLimits)
+		}
+	})
+
+	t.Run("takes the settings that used to be env only", func(t *testing.T) {
+		vars := map[string]string{
+			"CARDS_IDEMPOTENCY_WINDOW": "5m",
+			"CARDS_AUTH_SECRET":        "s3cr3t",
+			"CARDS_API_KEYS":           "k1=alice",
+		}
+		c, err := LoadConfig([]string{}, env(vars), io.Discard)
+		if err != nil || c.IdempotencyWindow != 5*time.Minute || c.AuthSecret != "s3cr3t" || c.APIKeys["k1"] != "alice" {
+			t.Errorf("Expected CARDS_* to be honored, found %+v (%v)", c, err)
+		}
+	})
+
+	t.Run("keeps secrets off the command line", func(t *testing.T) {
+		for _, args := range [][]string{{"--auth-secret", "s3cr3t"}, {"--api-keys", "k1=alice"}} {
+			if _, err := LoadConfig(args, env(nil), io.Discard); err == nil {
+				t.Errorf("Expected %v to be rejected", args[0])
+			}
+		}
+
+		path := filepath.Join(t.TempDir(), "cards.json")
+		os.WriteFile(path, []byte(`{"auth-secret": "s3cr3t"}`), 0o644)
+		c, err := LoadConfig([]string{"--config", path}, env(nil), io.Discard)
+		if err != nil || c.AuthSecret != "s3cr3t" {
+			t.Errorf("Expected the secret from the file, found %q (%v)", c.AuthSecret, err)
+		}
+
+		_, err = LoadConfig([]string{}, env(map[string]string{"CARDS_API_KEYS": "k1alice"}), io.Discard)
+		if err == nil || strings.Contains(err.Error(), "k1alice") {
+			t.Errorf("Expected malformed api keys to be rejected without repeating them, found %v", err)
+		}
+	})
+
+	t.Run("reports every invalid setting", func(t *testing.T) {
+		args := []string{"--storage", "file", "--tls-cert", "cert.pem", "--log-level", "loud", "--cors-origins", "a.com"}
+		_, err := LoadConfig(args, env(nil), io.Discard)
+		expected := []string{"storage-path", "tls-key go together", "log-level", `"a.com"`}
+		for _, e := range expected {
+			if err == nil || !strings.Contains(err.Error(), e) {
+				t.Errorf("Expected %v to be reported, found %v", e, err)
+			}
+		}
+	})
+
+	t.Run("rejects malformed values", func(t *testing.T) {
+		unknown := filepath.Join(t.TempDir(), "unknown.json")
+		os.WriteFile(unknown, []byte(`{"port": 8080}`), 0o644)
+
+		cases := []struct {
+			name string
+			args []string
+			vars map[string]string
+		}{
+			{"duration", []string{"--default-ttl", "soon"}, nil},
+			{"timeout", []string{"--write-timeout", "0s"}, nil},
+			{"credentials from any origin", []string{"--cors-credentials"}, nil},
+			{"log format", []string{"--log-format", "xml"}, nil},
+			{"rate limit of an unknown operation", []string{"--rate-limits", "create=1"}, nil},
+			{"rate limits", nil, map[string]string{"CARDS_RATE_LIMITS": "Create"}},
+			{"api keys", nil, map[string]string{"CARDS_API_KEYS": "alice"}},
+			{"missing config file", nil, map[string]string{"CARDS_CONFIG": "/nope.json"}},
+			{"unknown setting in the config file", []string{"--config", unknown}, nil},
+			{"argument", []string{"serve"}, nil},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				if _, err := LoadConfig(c.args, env(c.vars), io.Discard); err == nil {
+					t.Errorf("Expected %v to be rejected", c.name)
+				}
+			})
+		}
+	})
+
+	t.Run("answers help", func(t *testing.T) {
+		var usage bytes.Buffer
+		_, err := LoadConfig([]string{"--help"}, env(nil), &usage)
+		if !errors.Is(err, flag.ErrHelp) || !strings.Contains(usage.String(), "-storage-path") {
+			t.Errorf("Expected help, found %v", err)
+		}
+	})
+}
+
+func TestPrintConfig(t *testing.T) {
+	c := DefaultConfig()
+	c.AuthSecret = "s3cr3t"
+	c.APIKeys = map[string]string{"k1": "alice"}
+
+	var out bytes.Buffer
+	c.Print(&out)
+	printed := out.String()
+	if !strings.Contains(printed, "addr                 :8000\n") || !strings.Contains(printed, "default-ttl          24h0m0s\n") {
+		t.Errorf("Expected every setting, found\n%v", printed)
+	}
+	if strings.Contains(printed, "s3cr3t") || strings.Contains(printed, "k1") {
+		t.Errorf("Expected secrets to be left out, found\n%v", printed)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	c := DefaultConfig()
+	c.CORSOrigins = []string{"https://a.com"}
+	c.MaxDecksPerOwner = 1
+	c.APIKeys = map[string]string{"k1": "alice"}
+
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	c.apply(ctx)
+
+	t.Run("only lets allowed origins in", func(t *testing.T) {
+		for origin, expected := range map[string]string{"https://a.com": "https://a.com", "https://b.com": ""} {
+			r := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+			r.Header.Set("Origin", origin)
+			w := httptest.NewRecorder()
+			ctx.Routes().ServeHTTP(w, r)
+			if allowed := w.Header().Get("Access-Control-Allow-Origin"); allowed != expected {
+				t.Errorf("Expected %v to be allowed as %q, found %q", origin, expected, allowed)
+			}
+		}
+	})
+
+	t.Run("enforces the quota and api keys", func(t *testing.T) {
+		create := func() int {
+			r := httptest.NewRequest(http.MethodPost, "/v1/decks", nil)
+			r.Header.Set("X-API-Key", "k1")
+			w := httptest.NewRecorder()
+			ctx.Routes().ServeHTTP(w, r)
+			return w.Code
+		}
+		if first, second := create(), create(); first != http.StatusCreated || second != http.StatusConflict {
+			t.Errorf("Expected alice to be held to a single deck, found %v then %v", first, second)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// who browsers let call the API from other origins. Credentials are for
+// front-ends that rely on cookies (i.e. a session proxy in front of the
+// server), which is why they can't be combined with any origin
+type CORS struct {
+	// * allows any origin
+	Origins     []string
+	Credentials bool
+	// how long browsers may cache the answer to a preflight
+	MaxAge time.Duration
+}
+
+func DefaultCORS() CORS {
+	return CORS{Origins: []string{"*"}, MaxAge: 10 * time.Minute}
+}
+
+var (
+	// what browsers may send beyond the headers they always may
+	corsHeaders = []string{"Authorization", "Content-Type", "Idempotency-Key", "If-Match", "Last-Event-ID", "Traceparent", "X-API-Key", "X-Request-ID"}
+	// what scripts may read beyond the headers they always may
+	corsExposed = []string{"ETag", "Idempotent-Replayed", "Location", "Retry-After", "WWW-Authenticate", "X-Request-ID"}
+	// tried against the routes to find out what a path allows
+	corsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+)
+
+// what Access-Control-Allow-Origin should say, if anything
+func (c *CORS) allowedOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	if slices.Contains(c.Origins, origin) {
+		return origin, true
+	}
+	if slices.Contains(c.Origins, "*") {
+		return "*", true
+	}
+	return "", false
+}
+
+// answers depend on Origin unless any origin gets the same one
+func (ctx *HandlerContext) allowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	allowed, ok := ctx.crossOrigin.allowedOrigin(r.Header.Get("Origin"))
+	if allowed != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+	if !ok {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	if ctx.crossOrigin.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// lets browsers on the allowed origins read the responses
+func (ctx *HandlerContext) cors(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ctx.allowOrigin(w, r) {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposed, ", "))
+		}
+		next(w, r)
+	}
+}
+
+// OPTIONS on any path answers with the methods the routes allow there. With
+// Origin and Access-Control-Request-Method it's a preflight, which is only
+// let through for allowed origins, methods and headers
+func (ctx *HandlerContext) preflight(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		methods := []string{}
+		for _, method := range corsMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" {
+				methods = append(methods, method)
+			}
+		}
+		if len(methods) == 0 {
+			msg := fmt.Sprintf("There's nothing at %v", r.URL.Path)
+			writeError(w, http.StatusNotFound, errors.New(msg))
+			return
+		}
+		allow := strings.Join(append(methods, http.MethodOptions), ", ")
+		w.Header().Set("Allow", allow)
+
+		requested := r.Header.Get("Access-Control-Request-Method")
+		if r.Header.Get("Origin") == "" || requested == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if !ctx.allowOrigin(w, r) {
+			msg := fmt.Sprintf("Origin %v may not call the API", r.Header.Get("Origin"))
+			writeError(w, http.StatusForbidden, errors.New(msg))
+			return
+		}
+		if !slices.Contains(methods, requested) {
+			msg := fmt.Sprintf("%v only allows %v", r.URL.Path, allow)
+			writeError(w, http.StatusMethodNotAllowed, errors.New(msg))
+			return
+		}
+		for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			header = strings.TrimSpace(header)
+			if header != "" && !slices.ContainsFunc(corsHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+				msg := fmt.Sprintf("Header %v may not be sent from other origins", header)
+				writeError(w, http.StatusForbidden, errors.New(msg))
+				return
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsHeaders, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(ctx.crossOrigin.MaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.auth = NewAuthenticator([]byte("s3cr3t"), map[string]string{"alices-key": "alice"})
+	ctx.crossOrigin = CORS{Origins: []string{"https://app.example.com"}, Credentials: true, MaxAge: DefaultCORS().MaxAge}
+	draws := fmt.Sprintf("/v1/decks/%s/draws", uuid.New())
+
+	request := func(method string, url string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		ctx.Routes().ServeHTTP(w, r)
+		return w
+	}
+	preflight := func(origin string, method string, headers string) *httptest.ResponseRecorder {
+		return request(http.MethodOptions, draws, map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  method,
+			"Access-Control-Request-Headers": headers,
+		})
+	}
+
+	t.Run("answers preflights without credentials", func(t *testing.T) {
+		w := preflight("https://app.example.com", http.MethodPost, "x-api-key, if-match, idempotency-key")
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, found %v: %v", w.Code, w.Body)
+		}
+
+		expected := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "POST",
+			"Access-Control-Max-Age":           "600",
+		}
+		for name, value := range expected {
+			if found := w.Header().Get(name); found != value {
+				t.Errorf("Expected %v to be %v, found %q", name, value, found)
+			}
+		}
+		if allowed := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, "Idempotency-Key") || !strings.Contains(allowed, "If-Match") {
+			t.Errorf("Expected Idempotency-Key and If-Match to be allowed, found %v", allowed)
+		}
+	})
+
+	t.Run("turns preflights down", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			origin  string
+			method  string
+			headers string
+			status  int
+		}{
+			{"unknown origin", "https://evil.example.com", http.MethodPost, "", http.StatusForbidden},
+			{"method the route doesn't allow", "https://app.example.com", http.MethodDelete, "", http.StatusMethodNotAllowed},
+			{"header nobody reads", "https://app.example.com", http.MethodPost, "X-Forwarded-For", http.StatusForbidden},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				w := preflight(c.origin, c.method, c.headers)
+				if w.Code != c.status || w.Header().Get("Access-Control-Allow-Methods") != "" {
+					t.Errorf("Expected %v without allowing anything, found %v %v", c.status, w.Code, w.Header())
+				}
+			})
+		}
+	})
+
+	t.Run("answers plain OPTIONS with what a path allows", func(t *testing.T) {
+		w := request(http.MethodOptions, "/v1/games/highcard/"+uuid.NewString(), nil)
+		if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, OPTIONS" {
+			t.Errorf("Expected GET to be allowed, found %v %v", w.Code, w.Header().Get("Allow"))
+		}
+		if w := request(http.MethodOptions, "/nope", nil); w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a path that doesn't exist, found %v", w.Code)
+		}
+	})
+
+	t.Run("lets allowed origins read responses", func(t *testing.T) {
+		w := request(http.MethodPost, "/v1/decks", map[string]string{"Origin": "https://app.example.com", "X-API-Key": "alices-key"})
+		if w.Code != http.StatusCreated || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Errorf("Expected the origin to be allowed, found %v %v", w.Code, w.Header())
+		}
+		if exposed := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "ETag") {
+			t.Errorf("Expected ETag to be exposed, found %v", exposed)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("Expected the response to vary by origin, found %v", w.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("keeps other origins from reading responses", func(t *testing.T) {
+		w := request(http.MethodPost, "/v1/decks", map[string]string{"Origin": "https://evil.example.com", "X-API-Key": "alices-key"})
+		if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected the origin not to be allowed, found %v", w.Header())
+		}
+	})
+
+	t.Run("allows any origin by default", func(t *testing.T) {
+		ctx.crossOrigin = DefaultCORS()
+		w := preflight("https://anywhere.example.com", http.MethodPost, "authorization")
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected any origin to be allowed without credentials, found %v %v", w.Code, w.Header())
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// what responses can be encoded as, JSON first since it's what clients get
+// when they don't say or ask for something we can't do
+var mediaTypes = []string{
+	"application/json",
+	"text/plain",
+	"application/cbor",
+	"application/msgpack",
+	"application/x-msgpack",
+	"application/vnd.msgpack",
+}
+
+// responses with cards in them also come as a compact list of card codes,
+// as in AS KD 10H
+type texter interface {
+	text() string
+}
+
+// writes v in whichever of the media types r accepts best. Binary formats
+// carry the same fields as JSON, under the same names
+func respond[T any](w http.ResponseWriter, r *http.Request, status int, v T) {
+	offered := mediaTypes
+	t, isText := any(v).(texter)
+	if !isText {
+		offered = slices.DeleteFunc(slices.Clone(mediaTypes), func(mediaType string) bool { return mediaType == "text/plain" })
+	}
+
+	mediaType := negotiate(r.Header.Get("Accept"), offered)
+	var body []byte
+	var err error
+	switch mediaType {
+	case "text/plain":
+		body = []byte(t.text() + "\n")
+		mediaType += "; charset=utf-8"
+	case "application/cbor":
+		body, err = encodeTree(v, encodeCBOR)
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		body, err = encodeTree(v, encodeMsgpack)
+	default:
+		mediaType = "application/json"
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// the offered media type with the highest quality in accept, as in
+// text/plain;q=0.9, */*;q=0.1. Ties go to whichever was offered first and
+// nothing acceptable to "", which callers answer with JSON anyway rather
+// than with 406
+func negotiate(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		quality := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(name) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			quality = q
+		}
+		ranges = append(ranges, mediaRange{mediaType, quality})
+	}
+	// the most specific range decides: text/plain over text/* over */*
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	best, bestQuality := "", 0.0
+	for _, mediaType := range offered {
+		kind, _, _ := strings.Cut(mediaType, "/")
+		for _, r := range ranges {
+			if r.mediaType == mediaType || r.mediaType == kind+"/*" || r.mediaType == "*/*" {
+				if r.quality > bestQuality {
+					best, bestQuality = mediaType, r.quality
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+func (d CreatedDeck) text() string {
+	return d.Guid.String()
+}
+
+// the cards left in the deck, then a line per pile
+func (d OpenDeck) text() string {
+	lines := []string{cardCodes(d.Cards, d.RemainingCardCount)}
+	return strings.Join(append(lines, pileLines(d.Piles)...), "\n")
+}
+
+func (cards OpenCards) text() string {
+	return cardCodes(cards, len(cards))
+}
+
+// a line per pile, as in hand: AS KD
+func (v OpenView) text() string {
+	return strings.Join(pileLines(v.Piles), "\n")
+}
+
+// cards one may not see are only counted, as in (52)
+func cardCodes(cards []OpenCard, count int) string {
+	if len(cards) == 0 && count > 0 {
+		return fmt.Sprintf("(%d)", count)
+	}
+	codes := []string{}
+	for _, card := range cards {
+		codes = append(codes, card.Code)
+	}
+	return strings.Join(codes, " ")
+}
+
+func pileLines(piles map[string]OpenPile) []string {
+	names := []string{}
+	for name := range piles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		pile := piles[name]
+		count := len(pile.Cards)
+		if pile.Count != nil {
+			count = *pile.Count
+		}
+		lines = append(lines, strings.TrimSpace(name+": "+cardCodes(pile.Cards, count)))
+	}
+	return lines
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept   string
+		expected string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/plain", "text/plain"},
+		{"TEXT/PLAIN; charset=utf-8", "text/plain"},
+		{"application/cbor, application/json;q=0.5", "application/cbor"},
+		{"application/json;q=0.5, application/msgpack", "application/msgpack"},
+		{"application/x-msgpack", "application/x-msgpack"},
+		{"text/*, */*;q=0.1", "text/plain"},
+		{"application/*;q=0.2, text/plain;q=0.1", "application/json"},
+		{"*/*;q=0.5, application/json;q=0", "text/plain"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			if found := negotiate(c.accept, mediaTypes); found != c.expected {
+				t.Errorf("Expected %q, found %q", c.expected, found)
+			}
+		})
+	}
+}
+
+func TestBinaryEncodings(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   any
+		cbor    string
+		msgpack string
+	}{
+		{"small int", 10, "0a", "0a"},
+		{"int", 1000000, "1a000f4240", "ce000f4240"},
+		{"negative int", -1, "20", "ff"},
+		{"negative byte", -100, "3863", "d09c"},
+		{"float", 1.5, "fb3ff8000000000000", "cb3ff8000000000000"},
+		{"bools and null", []any{true, false, nil}, "83f5f4f6", "93c3c2c0"},
+		{"string", "AS", "624153", "a24153"},
+		{"map", map[string]any{"b": []int{2, 3}, "a": 1}, "a26161016162820203", "82a16101a162920203"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, encoding := range []struct {
+				name     string
+				encode   func(*bytes.Buffer, any) error
+				expected string
+			}{{"CBOR", encodeCBOR, c.cbor}, {"MessagePack", encodeMsgpack, c.msgpack}} {
+				found, err := encodeTree(c.value, encoding.encode)
+				expected, _ := hex.DecodeString(encoding.expected)
+				if err != nil || !bytes.Equal(found, expected) {
+					t.Errorf("Expected %v to be %x, found %x (%v)", encoding.name, expected, found, err)
+				}
+			}
+		})
+	}
+
+	t.Run("long strings", func(t *testing.T) {
+		long := string(bytes.Repeat([]byte("x"), 300))
+		cbor, _ := encodeTree(long, encodeCBOR)
+		msgpack, _ := encodeTree(long, encodeMsgpack)
+		if !bytes.HasPrefix(cbor, []byte{0x79, 0x01, 0x2c}) || !bytes.HasPrefix(msgpack, []byte{0xda, 0x01, 0x2c}) {
+			t.Errorf("Expected 16 bit lengths, found %x and %x", cbor[:3], msgpack[:3])
+		}
+	})
+}
+
+func TestContentNegotiation(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	request := func(method string, url string, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		ctx.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	w := request(http.MethodPost, "/v1/decks?cards=AS,KD,10H,QC&player=alice&visibility=owner", "text/plain")
+	id := w.Body.String()[:36]
+	if w.Code != http.StatusCreated || w.Body.String() != id+"\n" || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("Expected the deck id as text, found %v %q %v", w.Code, w.Body, w.Header().Get("Content-Type"))
+	}
+
+	t.Run("lists cards as text", func(t *testing.T) {
+		w := request(http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?count=3&player=alice", id), "text/plain")
+		if w.Body.String() != "KD 10H QC\n" || !slices.Contains(w.Header().Values("Vary"), "Accept") {
+			t.Errorf("Expected the drawn cards, found %q %v", w.Body, w.Header())
+		}
+
+		request(http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?count=1&pile=hand&player=alice", id), "")
+		if w := request(http.MethodGet, fmt.Sprintf("/v1/decks/%s?player=alice", id), "text/plain"); w.Body.String() != "\nhand: AS\n" {
+			t.Errorf("Expected the empty deck and alice's hand, found %q", w.Body)
+		}
+	})
+
+	t.Run("only counts cards one may not see", func(t *testing.T) {
+		w := request(http.MethodPost, "/v1/decks?player=alice&visibility=facedown", "text/plain")
+		other := w.Body.String()[:36]
+		if w := request(http.MethodGet, fmt.Sprintf("/v1/decks/%s?player=alice", other), "text/plain"); w.Body.String() != "(52)\n" {
+			t.Errorf("Expected the cards to be counted, found %q", w.Body)
+		}
+	})
+
+	t.Run("encodes binary formats", func(t *testing.T) {
+		for _, mediaType := range []string{"application/cbor", "application/msgpack", "application/vnd.msgpack"} {
+			w := request(http.MethodGet, fmt.Sprintf("/v1/decks/%s?player=alice", id), mediaType)
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != mediaType || !bytes.Contains(w.Body.Bytes(), []byte("deck_id")) {
+				t.Errorf("Expected %v with the JSON field names, found %v %v %x", mediaType, w.Code, w.Header().Get("Content-Type"), w.Body)
+			}
+		}
+	})
+
+	t.Run("answers with JSON otherwise", func(t *testing.T) {
+		w := request(http.MethodGet, fmt.Sprintf("/v1/decks/%s/history?player=alice", id), "text/plain")
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected events to fall back to JSON, found %v", w.Header().Get("Content-Type"))
+		}
+		w = request(http.MethodGet, fmt.Sprintf("/v1/decks/%s?player=alice", id), "image/png")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON rather than 406, found %v %v", w.Code, w.Header().Get("Content-Type"))
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"example.com/deck"
+	"example.com/game"
+	"fmt"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// something that happened to a deck or a game. Cards are only shown to
+// whoever is allowed to see them according to owner and visibility, and game
+// events carry the view of the game each player had right after the event
+type Event struct {
+	Seq        int        `json:"seq"`
+	Type       string     `json:"type"`
+	Guid       uuid.UUID  `json:"id"`
+	Actor      string     `json:"actor,omitempty"`
+	Time       time.Time  `json:"time"`
+	Pile       string     `json:"pile,omitempty"`
+	Count      int        `json:"count,omitempty"`
+	Targets    []int      `json:"targets,omitempty"`
+	Remaining  int        `json:"remaining"`
+	Cards      []OpenCard `json:"cards,omitempty"`
+	Players    []string   `json:"players,omitempty"`
+	Turn       string     `json:"turn,omitempty"`
+	View       *OpenView  `json:"view,omitempty"`
+	owner      string
+	visibility deck.Visibility
+	views      map[string]OpenView
+}
+
+func (e Event) For(player string) Event {
+	if !deck.FacesVisibleTo(e.owner, e.visibility, player) {
+		e.Cards = nil
+	}
+
+	// spectators get the view of the empty player
+	view, ok := e.views[player]
+	if !ok {
+		view, ok = e.views[""]
+	}
+	if ok {
+		e.View = &view
+	}
+	return e
+}
+
+// d must be the deck right after e happened
+func fromDeckEvent(d *deck.Deck, e deck.Event) Event {
+	cards := []deck.Card{}
+	for _, code := range e.Cards {
+		// the deck recorded the codes itself so they always parse
+		card, _ := deck.ParseCard(code)
+		cards = append(cards, card)
+	}
+
+	// undoing and redoing count the events they took back or did again
+	count := len(cards)
+	if len(e.Targets) > 0 {
+		count = len(e.Targets)
+	}
+
+	return Event{
+		Type:       string(e.Type),
+		Guid:       d.Guid,
+		Actor:      e.Actor,
+		Time:       e.Time,
+		Pile:       e.Pile,
+		Count:      count,
+		Targets:    e.Targets,
+		Remaining:  d.RemainingCardCount(),
+		Cards:      IntoOpenCards(cards),
+		Players:    e.Players,
+		owner:      e.Owner,
+		visibility: e.Visibility,
+	}
+}
+
+func gameEvent(eventType string, session *game.Session, actor string) Event {
+	views := map[string]OpenView{}
+	players := append([]game.PlayerID{""}, session.State.Players...)
+	for _, player := range players {
+		views[string(player)] = intoOpenView(session, session.ViewFor(player))
+	}
+
+	return Event{
+		Type:       eventType,
+		Guid:       session.Guid,
+		Actor:      actor,
+		Time:       time.Now().UTC(),
+		Remaining:  session.State.Stock.RemainingCardCount(),
+		Turn:       string(session.State.CurrentPlayer()),
+		visibility: deck.Hidden,
+		views:      views,
+	}
+}
+
+// a fixed size buffer keeping the most recent events so that clients that
+// lost their connection can pick up where they left off
+type ring struct {
+	events []Event
+	start  int
+	size   int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{make([]Event, capacity), 0, 0}
+}
+
+func (r *ring) push(e Event) {
+	capacity := len(r.events)
+	if r.size < capacity {
+		r.events[(r.start+r.size)%capacity] = e
+		r.size += 1
+		return
+	}
+	r.events[r.start] = e
+	r.start = (r.start + 1) % capacity
+}
+
+// events with a sequence number greater than seq, oldest first
+func (r *ring) since(seq int) []Event {
+	events := []Event{}
+	for i := 0; i < r.size; i += 1 {
+		e := r.events[(r.start+i)%len(r.events)]
+		if e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (r *ring) oldest() int {
+	if r.size == 0 {
+		return 0
+	}
+	return r.events[r.start].Seq
+}
+
+var (
+	ErrEventsGone   = errors.New("Requested events are no longer buffered")
+	ErrShuttingDown = errors.New("The server is shutting down")
+)
+
+type Subscriber struct {
+	Player string
+	Events chan Event
+	guid   uuid.UUID
+}
+
+type topic struct {
+	seq         int
+	buffer      *ring
+	subscribers map[*Subscriber]bool
+}
+
+type Broker struct {
+	mu          sync.Mutex
+	topics      map[uuid.UUID]*topic
+	bufferSize  int
+	backlogSize int
+	closed      bool
+}
+
+// bufferSize is how many events are kept around for resumption and
+// backlogSize how many undelivered events a subscriber may pile up before
+// being considered too slow and disconnected
+func NewBroker(bufferSize int, backlogSize int) *Broker {
+	return &Broker{
+		topics:      map[uuid.UUID]*topic{},
+		bufferSize:  bufferSize,
+		backlogSize: backlogSize,
+	}
+}
+
+func (b *Broker) topic(guid uuid.UUID) *topic {
+	t, ok := b.topics[guid]
+	if !ok {
+		t = &topic{0, newRing(b.bufferSize), map[*Subscriber]bool{}}
+		b.topics[guid] = t
+	}
+	return t
+}
+
+// publishing never blocks: a subscriber that can't keep up gets its channel
+// closed and is expected to reconnect and resume from the last seq it saw
+func (b *Broker) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(e.Guid)
+	t.seq += 1
+	e.Seq = t.seq
+	t.buffer.push(e)
+
+	for sub := range t.subscribers {
+		select {
+		case sub.Events <- e:
+		default:
+			delete(t.subscribers, sub)
+			close(sub.Events)
+		}
+	}
+	return e
+}
+
+// since is the last seq the client saw (0 for a fresh subscription). Events
+// after it are queued up right away so nothing falls through the cracks
+func (b *Broker) Subscribe(guid uuid.UUID, player string, since int) (*Subscriber, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrShuttingDown
+	}
+
+	t := b.topic(guid)
+	if since > t.seq {
+		msg := fmt.Sprintf("There's no event %d for %v yet", since, guid)
+		return nil, errors.New(msg)
+	}
+
+	if since > 0 && since < t.buffer.oldest()-1 {
+		return nil, ErrEventsGone
+	}
+
+	backlog := t.buffer.since(since)
+	capacity := b.backlogSize
+	if len(backlog) > capacity {
+		capacity = len(backlog)
+	}
+
+	sub := &Subscriber{player, make(chan Event, capacity), guid}
+	for _, e := range backlog {
+		sub.Events <- e
+	}
+	t.subscribers[sub] = true
+	return sub, nil
+}
+
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[sub.guid]
+	if ok && t.subscribers[sub] {
+		delete(t.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// ends the subscriptions of a deck or game that's no more
+func (b *Broker) Forget(guid uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[guid]
+	if !ok {
+		return
+	}
+	for sub := range t.subscribers {
+		close(sub.Events)
+	}
+	delete(b.topics, guid)
+}
+
+// ends every subscription so that streams terminate when the server stops
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, t := range b.topics {
+		for sub := range t.subscribers {
+			delete(t.subscribers, sub)
+			close(sub.Events)
+		}
+	}
+}
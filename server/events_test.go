@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	for seq := 1; seq <= 5; seq += 1 {
+		r.push(Event{Seq: seq})
+	}
+
+	events := r.since(0)
+	if len(events) != 3 || events[0].Seq != 3 || events[2].Seq != 5 {
+		t.Errorf("Expected ring to keep the 3 most recent events, found %v", events)
+	}
+
+	if events := r.since(4); len(events) != 1 || events[0].Seq != 5 {
+		t.Errorf("Expected only event 5 after 4, found %v", events)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	guid := uuid.New()
+
+	t.Run("delivers published events", func(t *testing.T) {
+		broker := NewBroker(10, 10)
+		sub, _ := broker.Subscribe(guid, "alice", 0)
+		broker.Publish(Event{Guid: guid, Type: "shuffled"})
+
+		e := <-sub.Events
+		if e.Seq != 1 || e.Type != "shuffled" {
+			t.Errorf("Expected first event to be the shuffle, found %v", e)
+		}
+	})
+
+	t.Run("resumes from sequence number", func(t *testing.T) {
+		broker := NewBroker(10, 10)
+		for i := 0; i < 5; i += 1 {
+			broker.Publish(Event{Guid: guid})
+		}
+
+		sub, _ := broker.Subscribe(guid, "alice", 3)
+		first, second := <-sub.Events, <-sub.Events
+		if first.Seq != 4 || second.Seq != 5 {
+			t.Errorf("Expected to resume with events 4 and 5, found %v and %v", first, second)
+		}
+	})
+
+	t.Run("refuses to resume from events no longer buffered", func(t *testing.T) {
+		broker := NewBroker(2, 10)
+		for i := 0; i < 5; i += 1 {
+			broker.Publish(Event{Guid: guid})
+		}
+
+		_, err := broker.Subscribe(guid, "alice", 1)
+		if !errors.Is(err, ErrEventsGone) {
+			t.Errorf("Expected events after 1 to be gone, found %v", err)
+		}
+	})
+
+	t.Run("disconnects slow subscribers", func(t *testing.T) {
+		broker := NewBroker(10, 2)
+		sub, _ := broker.Subscribe(guid, "alice", 0)
+		for i := 0; i < 3; i += 1 {
+			broker.Publish(Event{Guid: guid})
+		}
+
+		<-sub.Events
+		<-sub.Events
+		if _, ok := <-sub.Events; ok {
+			t.Errorf("Expected slow subscriber to have been disconnected")
+		}
+	})
+}
+
+func TestEventFiltering(t *testing.T) {
+	d := deck.NewDefaultDeck()
+	d.Visibility = deck.FaceDown
+	e := fromDeckEvent(&d, d.RecordDraw("alice", "", d.Draw(2)))
+
+	if len(e.For("alice").Cards) != 2 {
+		t.Errorf("Expected alice to see the cards she drew")
+	}
+
+	if e.For("bob").Cards != nil || e.For("bob").Count != 2 {
+		t.Errorf("Expected bob to only see how many cards alice drew")
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	server := httptest.NewServer(ctx.Routes())
+	defer server.Close()
+
+	created, _ := create(t, ctx, "/create?visibility=facedown")
+	wsUrl := strings.Replace(server.URL, "http", "ws", 1)
+
+	t.Run("pushes draws filtered for each player", func(t *testing.T) {
+		alice := dial(t, fmt.Sprintf("%s/ws/%s?player=alice", wsUrl, created.Guid))
+		defer alice.Close()
+		bob := dial(t, fmt.Sprintf("%s/ws/%s?player=bob", wsUrl, created.Guid))
+		defer bob.Close()
+
+		// both get the creation event right away which also means both are
+		// subscribed before drawing
+		readEvent(t, alice)
+		readEvent(t, bob)
+
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2&player=alice", created.Guid))
+
+		if e := readEvent(t, alice); e.Type != "drawn" || len(e.Cards) != 2 {
+			t.Errorf("Expected alice to see the 2 cards she drew, found %v", e)
+		}
+
+		if e := readEvent(t, bob); e.Type != "drawn" || e.Cards != nil || e.Count != 2 {
+			t.Errorf("Expected bob to only see that alice drew 2 cards, found %v", e)
+		}
+	})
+
+	t.Run("resumes after reconnecting", func(t *testing.T) {
+		conn := dial(t, fmt.Sprintf("%s/ws/%s?since=1", wsUrl, created.Guid))
+		defer conn.Close()
+
+		if e := readEvent(t, conn); e.Seq != 2 || e.Type != "drawn" {
+			t.Errorf("Expected to resume with the draw, found %v", e)
+		}
+	})
+
+	t.Run("fails to watch unknown deck", func(t *testing.T) {
+		url := fmt.Sprintf("%s/ws/%s", wsUrl, uuid.New())
+		_, res, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil || res.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 watching an unknown deck")
+		}
+	})
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial %v: %v", url, err)
+	}
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) Event {
+	var e Event
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&e); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	return e
+}
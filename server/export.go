@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GET /v1/decks/{guid}/export writes down the whole deck, hidden cards and history
+// included, so only the owner of the deck may export it and only as long as
+// it hides nothing from them, as in other players' hands or the order of a
+// face down deck. Decks without an owner may be exported by anyone they
+// hide nothing from
+func (ctx *HandlerContext) Export(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	d, err := retrieveDeck(ctx, r)
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	if err := authorizeExport(r, &d); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(d.Snapshot())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	filename := fmt.Sprintf("deck-%s.json", d.Guid)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(jsonBytes)
+}
+
+// POST /v1/imports?fresh=true&ttl=1h recreates the deck of the snapshot in the
+// body. The deck keeps its guid unless fresh is given, in which case it gets
+// a new one. Like with /create ttl is optional. The snapshot is read and
+// replayed before taking the lock, which is only held to insert the deck
+func (ctx *HandlerContext) Import(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, bodyStatus(err), err)
+		return
+	}
+
+	snapshot, err := deck.ParseSnapshot(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// otherwise anyone could plant decks in someone else's name
+	if err := authorizeOwner(r, snapshot.Owner); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	ttl, err := ctx.requestedTTL(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.ToLower(r.URL.Query().Get("fresh")) == "true" {
+		snapshot.Guid = uuid.New()
+	}
+
+	if err := ctx.checkDeckSize(len(snapshot.Cards)); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	d, err := snapshot.Restore()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if err := ctx.checkQuota(snapshot.Owner); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if _, ok := (*ctx.decks)[d.Guid]; ok {
+		msg := fmt.Sprintf("There's already a deck with identifier %v", d.Guid)
+		writeError(w, http.StatusConflict, errors.New(msg))
+		return
+	}
+
+	(*ctx.decks)[d.Guid] = d
+	delete(ctx.tombstones, d.Guid)
+	ctx.lease(d.Guid, ttl)
+	annotate(r, slog.String("deck", d.Guid.String()))
+	ctx.broker.Publish(Event{
+		Type:      "imported",
+		Guid:      d.Guid,
+		Actor:     requestingPlayer(r),
+		Time:      time.Now().UTC(),
+		Remaining: d.RemainingCardCount(),
+	})
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/decks/%s", d.Guid))
+	setETag(w, &d)
+	respond(w, r, http.StatusCreated, intoCreatedDeck(d))
+}
+
+// otherwise exporting and reading the snapshot would show cards that opening
+// the deck doesn't
+func authorizeExport(r *http.Request, d *deck.Deck) error {
+	if err := authorizeOwner(r, d.Owner); err != nil {
+		return err
+	}
+	if player := requestingPlayer(r); d.HidesCardsFrom(player) {
+		msg := fmt.Sprintf("Deck %v hides cards from %v", d.Guid, player)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func authorizeOwner(r *http.Request, owner string) error {
+	player := requestingPlayer(r)
+	if owner == "" || owner == player {
+		return nil
+	}
+	msg := fmt.Sprintf("Only %v may do this, not %v", owner, player)
+	return errors.New(msg)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	created, _ := create(t, ctx, "/create?shuffled=true&visibility=owner&player=alice")
+	draw(t, ctx, fmt.Sprintf("/draw/%s?count=3&pile=hand&player=alice", created.Guid))
+	snapshot := fetch(t, ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=alice", created.Guid))
+
+	t.Run("only owner may export", func(t *testing.T) {
+		w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=bob", created.Guid))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to export alice's deck, found %v", w.Code)
+		}
+	})
+
+	t.Run("only exports what the owner may see", func(t *testing.T) {
+		dealt, _ := create(t, ctx, "/create?visibility=owner&player=alice")
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/deals?players=bob&count=2&player=alice", dealt.Guid))
+		if w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=alice", dealt.Guid)); w.Code != http.StatusForbidden {
+			t.Errorf("Expected alice to be forbidden to export bob's hand, found %v", w.Code)
+		}
+
+		facedown, _ := create(t, ctx, "/create?shuffled=true&visibility=facedown&player=alice")
+		if w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=alice", facedown.Guid)); w.Code != http.StatusForbidden {
+			t.Errorf("Expected alice to be forbidden to export the order of a face down deck, found %v", w.Code)
+		}
+	})
+
+	t.Run("only exports unowned decks hiding nothing", func(t *testing.T) {
+		hidden, _ := create(t, ctx, "/create?shuffled=true&visibility=facedown")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2&pile=hand&player=alice", hidden.Guid))
+		w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=bob", hidden.Guid))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to export an unowned face down deck, found %v", w.Code)
+		}
+
+		public, _ := create(t, ctx, "/create")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2&pile=hand&player=alice", public.Guid))
+		if w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=bob", public.Guid)); w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to export a deck with alice's hand, found %v", w.Code)
+		}
+
+		public, _ = create(t, ctx, "/create")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2&pile=table&pile_visibility=public", public.Guid))
+		if w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/export/%s?player=bob", public.Guid)); w.Code != http.StatusOK {
+			t.Errorf("Expected anyone to export a public deck, found %v %v", w.Code, w.Body)
+		}
+	})
+
+	t.Run("refuses to import over an existing deck", func(t *testing.T) {
+		w := sendRequest(ctx, http.MethodPost, "/import?player=alice", nil, snapshot)
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected 409 importing a deck that exists, found %v", w.Code)
+		}
+	})
+
+	t.Run("imports with the same guid", func(t *testing.T) {
+		other := NewHandlerContext(&map[uuid.UUID]deck.Deck{})
+		w := sendRequest(other, http.MethodPost, "/import?player=alice", nil, snapshot)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected snapshot to be imported, found %v", w.Body.String())
+		}
+
+		imported := (*other.decks)[created.Guid]
+		original := decks[created.Guid]
+		if !cmp.Equal(imported.Cards, original.Cards) || !cmp.Equal(imported.Piles, original.Piles) {
+			t.Errorf("Expected imported deck to equal the exported one")
+		}
+	})
+
+	t.Run("imports with a fresh guid", func(t *testing.T) {
+		w := sendRequest(ctx, http.MethodPost, "/import?fresh=true&player=alice", nil, snapshot)
+		imported := decodeCreatedDeck(t, w.Body.String())
+		if imported.Guid == created.Guid || imported.RemainingCardCount != 49 {
+			t.Errorf("Expected a copy of the deck under a new guid, found %v", imported)
+		}
+	})
+
+	t.Run("only owner may import", func(t *testing.T) {
+		w := sendRequest(ctx, http.MethodPost, "/import?fresh=true&player=bob", nil, snapshot)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected bob to be forbidden to import alice's deck, found %v", w.Code)
+		}
+	})
+
+	t.Run("refuses snapshots of unknown versions", func(t *testing.T) {
+		v2 := strings.Replace(snapshot, `"version":1`, `"version":2`, 1)
+		w := sendRequest(ctx, http.MethodPost, "/import?fresh=true&player=alice", nil, v2)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected version 2 snapshot to be refused, found %v", w.Body.String())
+		}
+	})
+
+	t.Run("reads the snapshot before holding up everyone else", func(t *testing.T) {
+		finish := slowRequest(ctx, http.MethodPost, "/v1/imports?fresh=true&player=alice", snapshot)
+		assertNotBlocked(t, func() { fetchResponse(ctx, http.MethodPost, "/v1/decks") })
+		if w := finish(); w.Code != http.StatusCreated {
+			t.Errorf("Expected the slow import to succeed, found %v %v", w.Code, w.Body)
+		}
+	})
+
+	t.Run("rejects invalid snapshot", func(t *testing.T) {
+		w := sendRequest(ctx, http.MethodPost, "/import?fresh=true", nil, `{"version":1,"cards":["ZZ"]}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for invalid snapshot, found %v", w.Code)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"example.com/game"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// POST /v1/games/{type}?players=alice,bob&player=alice creates a session and
+// returns alice's view of it
+func (ctx *HandlerContext) CreateGame(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	players := []game.PlayerID{}
+	for _, name := range strings.Split(r.URL.Query().Get("players"), ",") {
+		if name != "" {
+			players = append(players, game.PlayerID(name))
+		}
+	}
+
+	session, err := game.NewSession(r.PathValue("type"), players)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	(*ctx.sessions)[session.Guid] = session
+	ctx.lease(session.Guid, ctx.retention.DefaultTTL)
+	annotate(r, slog.String("game", session.Guid.String()))
+	ctx.broker.Publish(gameEvent("created", session, requestingPlayer(r)))
+	w.Header().Set("Location", fmt.Sprintf("/v1/games/%s/%s", session.Type, session.Guid))
+
+	// whoever created the game without a seat at it only gets to watch
+	creator := game.PlayerID(requestingPlayer(r))
+	if !session.State.IsSeated(creator) {
+		creator = ""
+	}
+	writeView(w, r, http.StatusCreated, session, creator)
+}
+
+// GET /v1/games/{type}/{guid}?player=alice returns alice's view of the session
+func (ctx *HandlerContext) ViewGame(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	session, err := retrieveSession(ctx, r.PathValue("type"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	writeView(w, r, http.StatusOK, session, game.PlayerID(requestingPlayer(r)))
+}
+
+// POST /v1/games/{type}/{guid}/actions?player=alice applies the action in the body.
+// The body is read before taking the lock so that a slow client only holds
+// up itself
+func (ctx *HandlerContext) ActOnGame(w http.ResponseWriter, r *http.Request) {
+	var action game.Action
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		msg := fmt.Sprintf("Failed to parse action: %v", err)
+		writeError(w, bodyStatus(err), errors.New(msg))
+		return
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	session, err := retrieveSession(ctx, r.PathValue("type"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	ctx.act(w, r, session, game.PlayerID(requestingPlayer(r)), action)
+}
+
+func (ctx *HandlerContext) act(w http.ResponseWriter, r *http.Request, session *game.Session, player game.PlayerID, action game.Action) {
+	turn := session.State.CurrentPlayer()
+	if err := session.Act(player, action); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	ctx.broker.Publish(gameEvent("moved", session, string(player)))
+	if session.State.CurrentPlayer() != turn {
+		ctx.broker.Publish(gameEvent("turn_changed", session, string(player)))
+	}
+
+	writeView(w, r, http.StatusOK, session, player)
+}
+
+func retrieveSession(ctx *HandlerContext, gameType string, id string) (*game.Session, error) {
+	guid, err := parseGuid(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.expired(guid) {
+		return nil, fmt.Errorf("%w: %v", ErrGameExpired, guid)
+	}
+
+	session, ok := (*ctx.sessions)[guid]
+	if !ok || session.Type != gameType {
+		return nil, fmt.Errorf("%w: no %v game with identifier %v", ErrGameNotFound, gameType, guid)
+	}
+	ctx.touch(guid)
+	return session, nil
+}
+
+func writeView(w http.ResponseWriter, r *http.Request, status int, session *game.Session, player game.PlayerID) {
+	respond(w, r, status, intoOpenView(session, session.ViewFor(player)))
+}
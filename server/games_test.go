@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGames(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	t.Run("creates game", func(t *testing.T) {
+		view, err := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		if err != nil {
+			t.Fatalf("Expected game to be created, instead got %v", err)
+		}
+
+		hand := view.Piles["hand:alice"].Cards
+		if len(hand) != 5 {
+			t.Errorf("Expected alice to have been dealt 5 cards, found %v", hand)
+		}
+
+		if view.Turn != "alice" {
+			t.Errorf("Expected alice to play first, found %v instead", view.Turn)
+		}
+	})
+
+	t.Run("answers the creator with their own view", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=bob", "")
+		if view.Player != "bob" || view.Piles["hand:alice"].Cards != nil || len(view.Piles["hand:bob"].Cards) != 5 {
+			t.Errorf("Expected bob's view, found %v", view)
+		}
+
+		view, _ = playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=mallory", "")
+		if view.Player != "" || view.Piles["hand:alice"].Cards != nil || view.Piles["hand:bob"].Cards != nil {
+			t.Errorf("Expected mallory to only watch, found %v", view)
+		}
+	})
+
+	t.Run("fails to create game of unknown type", func(t *testing.T) {
+		_, err := playGame(t, ctx, http.MethodPost, "/games/nope?players=alice", "")
+		if err == nil {
+			t.Errorf("Expected err due to unknown game type")
+		}
+	})
+
+	t.Run("hides opponents hand", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		url := fmt.Sprintf("/games/highcard/%s?player=bob", view.Guid)
+		bobsView, _ := playGame(t, ctx, http.MethodGet, url, "")
+
+		alicesHand := bobsView.Piles["hand:alice"]
+		if alicesHand.Cards != nil {
+			t.Errorf("Expected bob not to see alice's cards %v", alicesHand.Cards)
+		}
+
+		if alicesHand.Count == nil || *alicesHand.Count != 5 {
+			t.Errorf("Expected bob to see alice holds 5 cards")
+		}
+	})
+
+	t.Run("never leaks hidden cards", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		session := (*ctx.sessions)[view.Guid]
+
+		hidden := append([]deck.Card{}, session.State.Hand("alice")...)
+		hidden = append(hidden, session.State.Stock.Cards...)
+
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			url := fmt.Sprintf("/games/highcard/%s?player=bob", view.Guid)
+			r := httptest.NewRequest(method, url, strings.NewReader("{}"))
+			w := httptest.NewRecorder()
+			ctx.Routes().ServeHTTP(w, r)
+			assertNoLeaks(t, w.Body.String(), hidden)
+		}
+	})
+
+	t.Run("plays legal action", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		action, _ := json.Marshal(view.Legal[0])
+		url := fmt.Sprintf("/games/highcard/%s?player=alice", view.Guid)
+		view, err := playGame(t, ctx, http.MethodPost, url, string(action))
+		if err != nil {
+			t.Fatalf("Expected action to be applied, instead got %v", err)
+		}
+
+		if len(view.Piles["hand:alice"].Cards) != 4 || view.Turn != "bob" {
+			t.Errorf("Expected alice to hold 4 cards and bob to be next, found %v", view)
+		}
+	})
+
+	t.Run("reads the action before holding up everyone else", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		action, _ := json.Marshal(view.Legal[0])
+		finish := slowRequest(ctx, http.MethodPost, fmt.Sprintf("/games/highcard/%s?player=alice", view.Guid), string(action))
+		assertNotBlocked(t, func() { fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/games/highcard/%s?player=bob", view.Guid)) })
+		if w := finish(); w.Code != http.StatusOK {
+			t.Errorf("Expected the slow action to be applied, found %v %v", w.Code, w.Body)
+		}
+	})
+
+	t.Run("rejects action out of turn", func(t *testing.T) {
+		view, _ := playGame(t, ctx, http.MethodPost, "/games/highcard?players=alice,bob&player=alice", "")
+		action, _ := json.Marshal(view.Legal[0])
+		url := fmt.Sprintf("/games/highcard/%s?player=bob", view.Guid)
+		_, err := playGame(t, ctx, http.MethodPost, url, string(action))
+		if err == nil {
+			t.Errorf("Expected err since it's not bob's turn")
+		}
+	})
+}
+
+func playGame(t *testing.T, ctx *HandlerContext, method string, url string, body string) (OpenView, error) {
+	w := sendRequest(ctx, method, url, nil, body)
+
+	res := w.Result()
+	defer res.Body.Close()
+	jsonBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return OpenView{}, err
+	}
+
+	if res.StatusCode >= 400 {
+		return OpenView{}, decodeProblem(jsonBytes)
+	}
+
+	var view OpenView
+	if err := json.Unmarshal(jsonBytes, &view); err != nil {
+		return OpenView{}, err
+	}
+	return view, nil
+}
@@ -1,12 +1,27 @@
 module example.com/server
 
-go 1.21.6
+go 1.25.0
+
+replace example.com/client => ../client
 
 replace example.com/deck => ../deck
 
+replace example.com/game => ../game
+
 require (
+	example.com/client v0.0.0-00010101000000-000000000000
 	example.com/deck v0.0.0-00010101000000-000000000000
+	example.com/game v0.0.0-00010101000000-000000000000
+	github.com/google/go-cmp v0.7.0
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478
+	google.golang.org/grpc v1.82.1
+	google.golang.org/protobuf v1.36.11
 )
 
-require github.com/google/go-cmp v0.6.0 // indirect
+require (
+	golang.org/x/net v0.53.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
+	golang.org/x/text v0.36.0 // indirect
+)
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example.com/server/cardspb"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeckService answers over gRPC what the /v1/decks routes answer over HTTP,
+// working on the same decks under the same lock
+type DeckService struct {
+	cardspb.UnimplementedDeckServiceServer
+	ctx *HandlerContext
+}
+
+// callers identify themselves with the same credentials as over HTTP, sent
+// as authorization (Bearer <jwt>) or x-api-key metadata. Without an
+// authenticator the player metadata tells who's calling. Calls are logged,
+// measured and rate limited like requests, by address before authentication
+// and by caller after
+func NewGRPCServer(ctx *HandlerContext, options ...grpc.ServerOption) *grpc.Server {
+	options = append(options,
+		grpc.ChainUnaryInterceptor(ctx.observedUnary, ctx.throttledUnary, ctx.authenticatedUnary, ctx.limitedUnary),
+		grpc.ChainStreamInterceptor(ctx.observedStream, ctx.throttledStream, ctx.authenticatedStream, ctx.limitedStream),
+	)
+	server := grpc.NewServer(options...)
+	cardspb.RegisterDeckServiceServer(server, &DeckService{ctx: ctx})
+	return server
+}
+
+func (s *DeckService) CreateDeck(c context.Context, req *cardspb.CreateDeckRequest) (*cardspb.CreatedDeck, error) {
+	s.ctx.mu.Lock()
+	defer s.ctx.mu.Unlock()
+
+	options := deckOptions{
+		cards:      strings.Join(req.Cards, ","),
+		shuffled:   req.Shuffled,
+		visibility: req.Visibility,
+		rules:      req.Rules,
+		ttl:        req.Ttl,
+	}
+	d, status, err := s.ctx.createDeck(callingPlayer(c), options)
+	if err != nil {
+		return nil, grpcError(status, err)
+	}
+
+	return &cardspb.CreatedDeck{
+		DeckId:    d.Guid.String(),
+		Shuffled:  d.IsShuffled(),
+		Remaining: int32(d.RemainingCardCount()),
+		Version:   int64(d.Version),
+	}, nil
+}
+
+func (s *DeckService) OpenDeck(c context.Context, req *cardspb.OpenDeckRequest) (*cardspb.Deck, error) {
+	s.ctx.mu.Lock()
+	defer s.ctx.mu.Unlock()
+
+	player := callingPlayer(c)
+	d, status, err := s.ctx.openDeck(req.DeckId, player)
+	if err != nil {
+		return nil, grpcError(status, err)
+	}
+	return intoDeckMessage(intoOpenDeck(d, player)), nil
+}
+
+func (s *DeckService) Draw(c context.Context, req *cardspb.DrawRequest) (*cardspb.DrawResponse, error) {
+	s.ctx.mu.Lock()
+	defer s.ctx.mu.Unlock()
+
+	// proto3 can't tell an unset count from 0 so 0 gets the default of 1
+	count := ""
+	if req.Count != 0 {
+		count = strconv.Itoa(int(req.Count))
+	}
+	options := drawOptions{
+		pile:           req.Pile,
+		pileVisibility: req.PileVisibility,
+		allowPartial:   req.AllowPartial,
+	}
+	if req.IfVersion != nil {
+		options.ifMatch = fmt.Sprintf(`"%d"`, *req.IfVersion)
+	}
+
+	d, cards, status, err := s.ctx.drawFrom(req.DeckId, callingPlayer(c), count, options)
+	if err != nil {
+		return nil, grpcError(status, err)
+	}
+
+	return &cardspb.DrawResponse{
+		Cards:   intoCardMessages(IntoOpenCards(cards)),
+		Version: int64(d.Version),
+	}, nil
+}
+
+// like the WebSocket and SSE endpoints, but for decks only
+func (s *DeckService) WatchDeck(req *cardspb.WatchDeckRequest, stream cardspb.DeckService_WatchDeckServer) error {
+	player := callingPlayer(stream.Context())
+	s.ctx.mu.Lock()
+	d, status, err := s.ctx.openDeck(req.DeckId, player)
+	s.ctx.mu.Unlock()
+	if err != nil {
+		return grpcError(status, err)
+	}
+
+	if req.Since < 0 {
+		since := strconv.FormatInt(req.Since, 10)
+		return grpcError(http.StatusBadRequest, &InputError{"since", since, ErrInvalidParameter})
+	}
+
+	sub, err := s.ctx.broker.Subscribe(d.Guid, player, int(req.Since))
+	if errors.Is(err, ErrEventsGone) {
+		return grpcError(http.StatusGone, err)
+	}
+	if errors.Is(err, ErrShuttingDown) {
+		return grpcError(http.StatusServiceUnavailable, err)
+	}
+	if err != nil {
+		return grpcError(http.StatusBadRequest, err)
+	}
+	defer s.ctx.broker.Unsubscribe(sub)
+
+	lastSeq := req.Since
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			if !ok {
+				msg := "Too slow, resume with since=%d"
+				return grpcError(http.StatusServiceUnavailable, fmt.Errorf(msg, lastSeq))
+			}
+			if err := stream.Send(intoEventMessage(e.For(player))); err != nil {
+				return err
+			}
+			lastSeq = int64(e.Seq)
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// the reason is the code a problem would carry so clients of both APIs can
+// switch on the same codes
+func grpcError(httpStatus int, err error) error {
+	problem := intoProblem(httpStatus, err)
+	info := &errdetails.ErrorInfo{
+		Reason:   problem.Code,
+		Domain:   "cards",
+		Metadata: map[string]string{},
+	}
+	if problem.Param != "" {
+		info.Metadata["param"] = problem.Param
+	}
+	if problem.Input != "" {
+		info.Metadata["input"] = problem.Input
+	}
+	if problem.Remaining != nil {
+		info.Metadata["remaining"] = strconv.Itoa(*problem.Remaining)
+	}
+
+	st := status.New(grpcCode(httpStatus), problem.Detail)
+	detailed, detailErr := st.WithDetails(info)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusPreconditionFailed:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+func (ctx *HandlerContext) authenticatedUnary(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	c, err := ctx.authenticateCall(c)
+	if err != nil {
+		return nil, err
+	}
+	return handler(c, req)
+}
+
+func (ctx *HandlerContext) authenticatedStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c, err := ctx.authenticateCall(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{stream, c})
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	c context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.c
+}
+
+func (ctx *HandlerContext) authenticateCall(c context.Context) (context.Context, error) {
+	if ctx.auth == nil {
+		return c, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(c)
+	token, _ := strings.CutPrefix(firstValue(md, "authorization"), "Bearer ")
+	principal, err := ctx.auth.AuthenticateCredentials(firstValue(md, "x-api-key"), token)
+	if err != nil {
+		return nil, grpcError(http.StatusUnauthorized, err)
+	}
+	return context.WithValue(c, principalKey{}, principal), nil
+}
+
+// gRPC methods are limited as the handlers answering the same over HTTP, so
+// that rate-limits such as Create=0.5 hold for both
+var grpcOperations = map[string]string{
+	"CreateDeck": "Create",
+	"OpenDeck":   "Open",
+	"Draw":       "Draw",
+	"WatchDeck":  "Events",
+}
+
+// as in Create for /cards.DeckService/CreateDeck
+func grpcOperation(fullMethod string) string {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if operation, ok := grpcOperations[method]; ok {
+		return operation
+	}
+	return method
+}
+
+// what logged and measure do for requests, for calls to fullMethod. Calls
+// are counted with the HTTP status their code stands for and method gRPC
+func (ctx *HandlerContext) observeCall(c context.Context, fullMethod string, id string, attrs []slog.Attr, start time.Time, err error) {
+	latency := time.Since(start)
+	// a watch ends with the context of the stream once its caller hangs up
+	st, ok := status.FromError(err)
+	if !ok {
+		st = status.FromContextError(err)
+	}
+	code := st.Code()
+	httpStatus := grpcHTTPStatus(code)
+	operation := grpcOperation(fullMethod)
+	ctx.metrics.observe(operation, "gRPC", httpStatus, latency)
+
+	attrs = append([]slog.Attr{
+		slog.String("request_id", id),
+		slog.String("operation", operation),
+		slog.String("method", "gRPC"),
+		slog.String("path", fullMethod),
+	}, attrs...)
+	attrs = append(attrs,
+		slog.Int("status", httpStatus),
+		slog.String("grpc_code", code.String()),
+		slog.Duration("latency", latency),
+	)
+
+	level := slog.LevelInfo
+	if httpStatus >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	ctx.logger.LogAttrs(c, level, "request", attrs...)
+}
+
+// the x-request-id of the call if it's a sensible one, a new one otherwise
+func callID(c context.Context) string {
+	md, _ := metadata.FromIncomingContext(c)
+	return sensibleRequestID(firstValue(md, "x-request-id"))
+}
+
+func (ctx *HandlerContext) observedUnary(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	id := callID(c)
+	grpc.SetHeader(c, metadata.Pairs("x-request-id", id))
+
+	attrs := []slog.Attr{}
+	if r, ok := req.(interface{ GetDeckId() string }); ok && r.GetDeckId() != "" {
+		attrs = append(attrs, slog.String("deck", r.GetDeckId()))
+	}
+	resp, err := handler(c, req)
+	if created, ok := resp.(*cardspb.CreatedDeck); ok && err == nil {
+		attrs = append(attrs, slog.String("deck", created.DeckId))
+	}
+	ctx.observeCall(c, info.FullMethod, id, attrs, start, err)
+	return resp, err
+}
+
+// streams are logged and measured once they're over
+func (ctx *HandlerContext) observedStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	id := callID(stream.Context())
+	stream.SetHeader(metadata.Pairs("x-request-id", id))
+
+	observed := &observedStream{ServerStream: stream}
+	err := handler(srv, observed)
+	attrs := []slog.Attr{}
+	if observed.deck != "" {
+		attrs = append(attrs, slog.String("deck", observed.deck))
+	}
+	ctx.observeCall(stream.Context(), info.FullMethod, id, attrs, start, err)
+	return err
+}
+
+// keeps the deck the request of a stream is about
+type observedStream struct {
+	grpc.ServerStream
+	deck string
+}
+
+func (s *observedStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if r, ok := m.(interface{ GetDeckId() string }); ok && err == nil {
+		s.deck = r.GetDeckId()
+	}
+	return err
+}
+
+// the inverse of grpcCode. Callers hanging up are 499 as nginx has it, so
+// that a watch ending as it should isn't counted as an error
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.Aborted:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// what throttled and limited do for requests, for calls to fullMethod
+func (ctx *HandlerContext) throttleCall(c context.Context, fullMethod string) error {
+	address := ""
+	if p, ok := peer.FromContext(c); ok {
+		address = p.Addr.String()
+	}
+	if err := ctx.throttle(grpcOperation(fullMethod), "ip:"+remoteHost(address)); err != nil {
+		return grpcRateLimited(err)
+	}
+	return nil
+}
+
+func (ctx *HandlerContext) limitCall(c context.Context, fullMethod string) error {
+	if principal, ok := c.Value(principalKey{}).(string); ok {
+		if err := ctx.throttle(grpcOperation(fullMethod), "principal:"+principal); err != nil {
+			return grpcRateLimited(err)
+		}
+	}
+	return nil
+}
+
+func (ctx *HandlerContext) throttledUnary(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := ctx.throttleCall(c, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(c, req)
+}
+
+func (ctx *HandlerContext) limitedUnary(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := ctx.limitCall(c, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(c, req)
+}
+
+// streams are limited once, when they're opened
+func (ctx *HandlerContext) throttledStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := ctx.throttleCall(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+func (ctx *HandlerContext) limitedStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := ctx.limitCall(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// ResourceExhausted with a RetryInfo standing in for Retry-After
+func grpcRateLimited(err *RateLimitedError) error {
+	st := status.Convert(grpcError(http.StatusTooManyRequests, err))
+	delay := durationpb.New(time.Duration(err.retryAfter()) * time.Second)
+	detailed, detailErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: delay})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func callingPlayer(c context.Context) string {
+	if principal, ok := c.Value(principalKey{}).(string); ok {
+		return principal
+	}
+	md, _ := metadata.FromIncomingContext(c)
+	return firstValue(md, "player")
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func intoDeckMessage(d OpenDeck) *cardspb.Deck {
+	piles := map[string]*cardspb.Pile{}
+	for name, pile := range d.Piles {
+		message := &cardspb.Pile{
+			Owner:      pile.Owner,
+			Visibility: pile.Visibility,
+			Cards:      intoCardMessages(pile.Cards),
+		}
+		if pile.Count != nil {
+			count := int32(*pile.Count)
+			message.Count = &count
+		}
+		piles[name] = message
+	}
+
+	return &cardspb.Deck{
+		DeckId:     d.Guid.String(),
+		Shuffled:   d.IsShuffled,
+		Remaining:  int32(d.RemainingCardCount),
+		Owner:      d.Owner,
+		SharedWith: d.SharedWith,
+		Visibility: d.Visibility,
+		Cards:      intoCardMessages(d.Cards),
+		Piles:      piles,
+		Version:    int64(d.Version),
+	}
+}
+
+func intoCardMessages(cards []OpenCard) []*cardspb.Card {
+	messages := []*cardspb.Card{}
+	for _, card := range cards {
+		messages = append(messages, &cardspb.Card{Value: card.Rank, Suit: card.Suit, Code: card.Code})
+	}
+	return messages
+}
+
+func intoEventMessage(e Event) *cardspb.DeckEvent {
+	return &cardspb.DeckEvent{
+		Seq:       int64(e.Seq),
+		Type:      e.Type,
+		DeckId:    e.Guid.String(),
+		Actor:     e.Actor,
+		Time:      timestamppb.New(e.Time),
+		Pile:      e.Pile,
+		Count:     int32(e.Count),
+		Remaining: int32(e.Remaining),
+		Cards:     intoCardMessages(e.Cards),
+		Players:   e.Players,
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"example.com/deck"
+	"example.com/server/cardspb"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeckService(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	client := dialDeckService(t, ctx)
+	alice := asPlayer("alice")
+
+	created, err := client.CreateDeck(alice, &cardspb.CreateDeckRequest{
+		Cards:      []string{"AS", "2S", "3S", "4S", "5S"},
+		Visibility: "owner",
+	})
+	if err != nil {
+		t.Fatalf("Expected alice to create a deck, found %v", err)
+	}
+
+	t.Run("creates deck", func(t *testing.T) {
+		if created.Remaining != 5 || created.Shuffled || created.Version != 1 {
+			t.Errorf("Expected an unshuffled deck of 5 cards, found %v", created)
+		}
+	})
+
+	t.Run("works on the same decks as the HTTP API", func(t *testing.T) {
+		opened, err := open(t, ctx, fmt.Sprintf("/open/%s?player=alice", created.DeckId))
+		if err != nil || opened.RemainingCardCount != 5 || opened.Owner != "alice" {
+			t.Errorf("Expected alice's deck to be open over HTTP, found %v (%v)", opened, err)
+		}
+	})
+
+	t.Run("opens deck", func(t *testing.T) {
+		d, err := client.OpenDeck(alice, &cardspb.OpenDeckRequest{DeckId: created.DeckId})
+		if err != nil {
+			t.Fatalf("Expected alice to open her deck, found %v", err)
+		}
+		if d.Owner != "alice" || len(d.Cards) != 5 || d.Cards[0].Code != "AS" {
+			t.Errorf("Expected alice to see all 5 cards, found %v", d)
+		}
+	})
+
+	t.Run("draws cards", func(t *testing.T) {
+		drawn, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId, Count: 2, Pile: "hand"})
+		if err != nil {
+			t.Fatalf("Expected alice to draw, found %v", err)
+		}
+		if len(drawn.Cards) != 2 || drawn.Cards[0].Code != "4S" || drawn.Version != 2 {
+			t.Errorf("Expected alice to draw 4S and 5S, found %v", drawn)
+		}
+
+		d, _ := client.OpenDeck(alice, &cardspb.OpenDeckRequest{DeckId: created.DeckId})
+		if hand := d.Piles["hand"]; hand == nil || len(hand.Cards) != 2 {
+			t.Errorf("Expected the drawn cards in alice's hand, found %v", d.Piles)
+		}
+	})
+
+	t.Run("draws one card by default", func(t *testing.T) {
+		drawn, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId})
+		if err != nil || len(drawn.Cards) != 1 {
+			t.Errorf("Expected to draw a single card, found %v (%v)", drawn, err)
+		}
+	})
+
+	t.Run("answers errors with codes and reasons", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			call   func() error
+			code   codes.Code
+			reason string
+		}{
+			{"invalid card", func() error {
+				_, err := client.CreateDeck(alice, &cardspb.CreateDeckRequest{Cards: []string{"A?"}})
+				return err
+			}, codes.InvalidArgument, "invalid_card"},
+			{"invalid guid", func() error {
+				_, err := client.OpenDeck(alice, &cardspb.OpenDeckRequest{DeckId: "nope"})
+				return err
+			}, codes.InvalidArgument, "invalid_guid"},
+			{"unknown deck", func() error {
+				_, err := client.OpenDeck(alice, &cardspb.OpenDeckRequest{DeckId: uuid.NewString()})
+				return err
+			}, codes.NotFound, "deck_not_found"},
+			{"someone else's deck", func() error {
+				_, err := client.OpenDeck(asPlayer("bob"), &cardspb.OpenDeckRequest{DeckId: created.DeckId})
+				return err
+			}, codes.PermissionDenied, "forbidden"},
+			{"negative count", func() error {
+				_, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId, Count: -1})
+				return err
+			}, codes.InvalidArgument, "invalid_count"},
+			{"not enough cards", func() error {
+				_, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId, Count: 9})
+				return err
+			}, codes.FailedPrecondition, "not_enough_cards"},
+			{"stale version", func() error {
+				stale := int64(1)
+				_, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId, IfVersion: &stale})
+				return err
+			}, codes.Aborted, "version_mismatch"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				code, reason := errorReason(c.call())
+				if code != c.code || reason != c.reason {
+					msg := "Expected %v (%v), found %v (%v) instead"
+					t.Errorf(msg, c.code, c.reason, code, reason)
+				}
+			})
+		}
+	})
+
+	t.Run("draws what's left when allowed to", func(t *testing.T) {
+		drawn, err := client.Draw(alice, &cardspb.DrawRequest{DeckId: created.DeckId, Count: 9, AllowPartial: true})
+		if err != nil || len(drawn.Cards) != 2 {
+			t.Errorf("Expected to draw the last 2 cards, found %v (%v)", drawn, err)
+		}
+	})
+}
+
+func TestWatchDeck(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	client := dialDeckService(t, ctx)
+
+	// nobody owns the deck so anyone may watch it
+	created, _ := client.CreateDeck(context.Background(), &cardspb.CreateDeckRequest{Visibility: "facedown"})
+
+	watch := func(t *testing.T, player string, since int64) cardspb.DeckService_WatchDeckClient {
+		c, cancel := context.WithTimeout(asPlayer(player), 5*time.Second)
+		t.Cleanup(cancel)
+		stream, err := client.WatchDeck(c, &cardspb.WatchDeckRequest{DeckId: created.DeckId, Since: since})
+		if err != nil {
+			t.Fatalf("Expected to watch the deck, found %v", err)
+		}
+		return stream
+	}
+
+	t.Run("streams draws made over HTTP", func(t *testing.T) {
+		stream := watch(t, "alice", 0)
+		if e, err := stream.Recv(); err != nil || e.Type != "created" {
+			t.Fatalf("Expected the creation event first, found %v (%v)", e, err)
+		}
+
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2&player=alice", created.DeckId))
+
+		e, err := stream.Recv()
+		if err != nil || e.Type != "drawn" || len(e.Cards) != 2 || e.Remaining != 50 {
+			t.Errorf("Expected alice to see the 2 cards she drew, found %v (%v)", e, err)
+		}
+	})
+
+	t.Run("hides faces from other players", func(t *testing.T) {
+		stream := watch(t, "bob", 1)
+		e, err := stream.Recv()
+		if err != nil || e.Seq != 2 || e.Count != 2 || len(e.Cards) != 0 {
+			t.Errorf("Expected bob to resume with a faceless draw, found %v (%v)", e, err)
+		}
+	})
+
+	t.Run("fails to watch unknown deck", func(t *testing.T) {
+		stream, _ := client.WatchDeck(context.Background(), &cardspb.WatchDeckRequest{DeckId: uuid.NewString()})
+		_, err := stream.Recv()
+		if code, _ := errorReason(err); code != codes.NotFound {
+			t.Errorf("Expected NotFound watching an unknown deck, found %v", err)
+		}
+	})
+}
+
+func TestAuthenticatedDeckService(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.auth = NewAuthenticator([]byte("s3cr3t"), map[string]string{"carols-key": "carol"})
+	client := dialDeckService(t, ctx)
+
+	t.Run("rejects missing credentials", func(t *testing.T) {
+		_, err := client.CreateDeck(asPlayer("alice"), &cardspb.CreateDeckRequest{})
+		if code, _ := errorReason(err); code != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated without credentials, found %v", err)
+		}
+	})
+
+	t.Run("accepts bearer tokens", func(t *testing.T) {
+		token, _ := ctx.auth.Sign("alice", time.Now().Add(time.Hour))
+		c := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+		created, err := client.CreateDeck(c, &cardspb.CreateDeckRequest{})
+		if err != nil {
+			t.Fatalf("Expected alice to create a deck, found %v", err)
+		}
+
+		d, _ := client.OpenDeck(c, &cardspb.OpenDeckRequest{DeckId: created.DeckId})
+		if d.GetOwner() != "alice" {
+			t.Errorf("Expected the deck to belong to alice, found %v", d.GetOwner())
+		}
+	})
+
+	t.Run("accepts api keys", func(t *testing.T) {
+		c := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "carols-key")
+		if _, err := client.CreateDeck(c, &cardspb.CreateDeckRequest{}); err != nil {
+			t.Errorf("Expected carol to create a deck, found %v", err)
+		}
+	})
+}
+
+func TestRateLimitedDeckService(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.limits = Limits{Rate: 1, Burst: 1, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx.now = func() time.Time { return now }
+	ctx.auth = NewAuthenticator(nil, map[string]string{"carols-key": "carol"})
+	client := dialDeckService(t, ctx)
+
+	guess := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "guess")
+	if _, err := client.CreateDeck(guess, &cardspb.CreateDeckRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected the guess to be refused, found %v", err)
+	}
+
+	carol := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "carols-key")
+	_, err := client.CreateDeck(carol, &cardspb.CreateDeckRequest{})
+	if code, reason := errorReason(err); code != codes.ResourceExhausted || reason != "rate_limited" {
+		t.Fatalf("Expected the address to be limited, found %v", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry.GetRetryDelay().AsDuration() != time.Second {
+		t.Errorf("Expected to be told to retry in a second, found %v", retry)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := client.CreateDeck(carol, &cardspb.CreateDeckRequest{}); err != nil {
+		t.Errorf("Expected carol to be allowed a second later, found %v", err)
+	}
+	if _, err := client.OpenDeck(carol, &cardspb.OpenDeckRequest{DeckId: uuid.NewString()}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected carol to be limited, found %v", err)
+	}
+
+	watch := func() error {
+		stream, err := client.WatchDeck(carol, &cardspb.WatchDeckRequest{DeckId: uuid.NewString()})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+	if code, reason := errorReason(watch()); code != codes.ResourceExhausted || reason != "rate_limited" {
+		t.Errorf("Expected watching to be limited as well, found %v %v", code, reason)
+	}
+	now = now.Add(time.Second)
+	if err := watch(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected carol to get to watch a second later, found %v", err)
+	}
+}
+
+func TestObservedDeckService(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	var logs bytes.Buffer
+	ctx.logger = NewLogger(&logs, "json", "info")
+	client := dialDeckService(t, ctx)
+
+	lastEntry := func(t *testing.T) map[string]any {
+		t.Helper()
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+			t.Fatalf("Expected a JSON log line, found %q: %v", logs.String(), err)
+		}
+		return entry
+	}
+
+	var header metadata.MD
+	c := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "call-1")
+	created, err := client.CreateDeck(c, &cardspb.CreateDeckRequest{Cards: []string{"AS", "KD"}}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Expected to create a deck, found %v", err)
+	}
+	missing := uuid.NewString()
+	client.OpenDeck(context.Background(), &cardspb.OpenDeckRequest{DeckId: missing})
+	stream, _ := client.WatchDeck(context.Background(), &cardspb.WatchDeckRequest{DeckId: missing})
+	stream.Recv()
+
+	t.Run("logs calls as requests", func(t *testing.T) {
+		if id := firstValue(header, "x-request-id"); id != "call-1" {
+			t.Errorf("Expected the request id to be sent back, found %q", id)
+		}
+		var entry map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			json.Unmarshal([]byte(line), &entry)
+			if entry["operation"] == "Create" {
+				break
+			}
+		}
+		expected := map[string]any{
+			"msg":        "request",
+			"request_id": "call-1",
+			"operation":  "Create",
+			"method":     "gRPC",
+			"path":       "/cards.v1.DeckService/CreateDeck",
+			"deck":       created.DeckId,
+			"status":     float64(http.StatusOK),
+			"grpc_code":  "OK",
+		}
+		for key, value := range expected {
+			if entry[key] != value {
+				t.Errorf("Expected %v to be %v, found %v", key, value, entry[key])
+			}
+		}
+		if strings.Contains(logs.String(), "KD") {
+			t.Errorf("Expected the cards to stay out of the logs, found %v", logs.String())
+		}
+	})
+
+	t.Run("logs streams once they're over", func(t *testing.T) {
+		entry := lastEntry(t)
+		if entry["operation"] != "Events" || entry["deck"] != missing || entry["grpc_code"] != "NotFound" {
+			t.Errorf("Expected the failed watch to be logged, found %v", entry)
+		}
+	})
+
+	t.Run("measures calls as requests", func(t *testing.T) {
+		body := fetchResponse(ctx, http.MethodGet, "/metrics").Body.String()
+		expected := []string{
+			`cards_http_requests_total{handler="Create",method="gRPC",code="200"} 1`,
+			`cards_http_requests_total{handler="Open",method="gRPC",code="404"} 1`,
+			`cards_http_requests_total{handler="Events",method="gRPC",code="404"} 1`,
+			`cards_http_request_duration_seconds_count{handler="Open"} 1`,
+		}
+		for _, line := range expected {
+			if !strings.Contains(body, line) {
+				t.Errorf("Expected %v in %v", line, body)
+			}
+		}
+	})
+}
+
+// the server runs in process and is stopped once the test is over
+func dialDeckService(t *testing.T, ctx *HandlerContext) cardspb.DeckServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(ctx)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial the deck service: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return cardspb.NewDeckServiceClient(conn)
+}
+
+func asPlayer(player string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "player", player)
+}
+
+func errorReason(err error) (codes.Code, string) {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info.Reason
+		}
+	}
+	return st.Code(), ""
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type Health struct {
+	Status string `json:"status"`
+	// what was checked and what's wrong with it, ok when nothing
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// GET /healthz answers as long as the process serves requests at all
+func (ctx *HandlerContext) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, Health{Status: "ok"})
+}
+
+// GET /readyz answers 503 once the server is shutting down or when decks
+// couldn't be saved, so that load balancers send requests elsewhere
+func (ctx *HandlerContext) Readyz(w http.ResponseWriter, r *http.Request) {
+	health := Health{Status: "ok", Checks: map[string]string{"storage": "ok", "shutdown": "ok"}}
+	status := http.StatusOK
+
+	ctx.mu.Lock()
+	err := ctx.flushErr
+	ctx.mu.Unlock()
+	if err == nil {
+		err = ctx.store.Ping()
+	}
+	if err != nil {
+		health.Checks["storage"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	if ctx.draining.Load() {
+		health.Checks["shutdown"] = "draining"
+		status = http.StatusServiceUnavailable
+	}
+	if status != http.StatusOK {
+		health.Status = "unavailable"
+	}
+	writeHealth(w, status, health)
+}
+
+func writeHealth(w http.ResponseWriter, status int, health Health) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
+}
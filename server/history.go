@@ -0,0 +1,70 @@
+package main
+
+import (
+	"example.com/deck"
+	"net/http"
+	"strconv"
+)
+
+// GET /v1/decks/{guid}/history lists everything that happened to a deck. Everyone,
+// the owner of the deck included, only sees the cards they could see when
+// the events happened
+// GET /v1/decks/{guid}/history?at=3 rebuilds the deck as it was right after event 3
+func (ctx *HandlerContext) History(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	d, err := retrieveDeck(ctx, r)
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	if err := authorize(r, &d); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	player := requestingPlayer(r)
+	if param := r.URL.Query().Get("at"); param != "" {
+		seq, err := strconv.Atoi(param)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &InputError{"at", param, ErrInvalidParameter})
+			return
+		}
+
+		past, err := d.AsOf(seq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		respond(w, r, http.StatusOK, intoOpenDeck(past, player))
+		return
+	}
+
+	events, err := replayEvents(&d, player)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, events)
+}
+
+// replaying is what tells us how many cards remained after each event
+func replayEvents(d *deck.Deck, player string) ([]Event, error) {
+	events := []Event{}
+	past := deck.NewEmptyDeck()
+	past.Guid = d.Guid
+	for _, e := range d.History {
+		if err := past.Apply(e); err != nil {
+			return []Event{}, err
+		}
+
+		event := fromDeckEvent(&past, e)
+		event.Seq = e.Seq
+		events = append(events, event.For(player))
+	}
+	return events, nil
+}
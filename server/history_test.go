@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	created, _ := create(t, ctx, "/create?visibility=facedown&player=alice")
+	fetch(t, ctx, http.MethodPost, fmt.Sprintf("/shuffle/%s?player=alice", created.Guid))
+	fetch(t, ctx, http.MethodPost, fmt.Sprintf("/share/%s?with=bob&player=alice", created.Guid))
+	draw(t, ctx, fmt.Sprintf("/draw/%s?count=3&player=bob", created.Guid))
+
+	t.Run("lists every event", func(t *testing.T) {
+		url := fmt.Sprintf("/history/%s?player=alice", created.Guid)
+		events := history(t, ctx, url)
+		if len(events) != 4 {
+			t.Fatalf("Expected 4 events, found %v", events)
+		}
+
+		types := []string{"created", "shuffled", "shared", "drawn"}
+		for i, e := range events {
+			if e.Seq != i+1 || e.Type != types[i] {
+				t.Errorf("Expected event %d to be %v, found %v", i+1, types[i], e)
+			}
+		}
+
+		if events[3].Actor != "bob" || events[3].Remaining != 49 {
+			t.Errorf("Expected bob to have drawn leaving 49 cards, found %v", events[3])
+		}
+	})
+
+	t.Run("owner only sees what they saw", func(t *testing.T) {
+		url := fmt.Sprintf("/history/%s?player=alice", created.Guid)
+		events := history(t, ctx, url)
+		if events[3].Cards != nil || events[3].Count != 3 {
+			t.Errorf("Expected alice to only count the cards bob drew, as opening the deck does, found %v", events[3])
+		}
+	})
+
+	t.Run("other players only see what they saw", func(t *testing.T) {
+		url := fmt.Sprintf("/history/%s?player=bob", created.Guid)
+		events := history(t, ctx, url)
+		if events[0].Cards != nil || events[1].Cards != nil {
+			t.Errorf("Expected bob not to see the order of a face down deck")
+		}
+
+		if len(events[3].Cards) != 3 {
+			t.Errorf("Expected bob to see the cards he drew")
+		}
+	})
+
+	t.Run("rebuilds deck as of an event", func(t *testing.T) {
+		url := fmt.Sprintf("/history/%s?at=3&player=alice", created.Guid)
+		var openDeck OpenDeck
+		json.Unmarshal([]byte(fetch(t, ctx, http.MethodGet, url)), &openDeck)
+		if openDeck.RemainingCardCount != 52 || !openDeck.IsShuffled {
+			t.Errorf("Expected a full shuffled deck before bob drew, found %v", openDeck)
+		}
+	})
+
+	t.Run("fails to rebuild deck as of an unknown event", func(t *testing.T) {
+		url := fmt.Sprintf("/history/%s?at=10&player=alice", created.Guid)
+		r := fetchResponse(ctx, http.MethodGet, url)
+		if r.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for unknown event, found %v", r.Code)
+		}
+	})
+}
+
+func history(t *testing.T, ctx *HandlerContext, url string) []Event {
+	var events []Event
+	body := fetch(t, ctx, http.MethodGet, url)
+	if err := json.Unmarshal([]byte(body), &events); err != nil {
+		t.Fatalf("Failed to decode %v: %v", body, err)
+	}
+	return events
+}
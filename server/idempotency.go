@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+var ErrIdempotencyKeyReused = errors.New("Idempotency key was used for a different request")
+
+const maxIdempotencyKeyLength = 255
+
+// keys are only unique within what a player does to a deck so two players
+// (or one player on two decks) may pick the same key
+type idempotencyScope struct {
+	principal string
+	resource  string
+	key       string
+}
+
+// status stays 0 while the original request is still being handled
+type storedResponse struct {
+	fingerprint string
+	storedAt    time.Time
+	done        chan struct{}
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+func (s *storedResponse) finished() bool {
+	return s.status != 0
+}
+
+func (s *storedResponse) replay(w http.ResponseWriter) {
+	for name, values := range s.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(s.status)
+	w.Write(s.body)
+}
+
+// passes everything through while keeping a copy to replay later
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// retries carrying the Idempotency-Key of a request that already went
+// through get the original response back instead of doing it all over
+// again, as long as they come within the idempotency window. Responses to
+// requests that failed on our side aren't kept so that retrying them helps
+func (ctx *HandlerContext) idempotent(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			handler(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, http.StatusBadRequest, &InputError{"Idempotency-Key", key, ErrInvalidParameter})
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, bodyStatus(err), err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope{requestingPlayer(r), r.PathValue("id"), key}
+		fingerprint := fingerprintRequest(r, body)
+		for {
+			stored, isRetry := ctx.storeResponse(scope, fingerprint)
+			if !isRetry {
+				ctx.record(w, r, handler, scope, stored)
+				return
+			}
+
+			if stored.fingerprint != fingerprint {
+				writeError(w, http.StatusConflict, ErrIdempotencyKeyReused)
+				return
+			}
+
+			<-stored.done
+			ctx.mu.Lock()
+			finished := stored.finished()
+			ctx.mu.Unlock()
+			if finished {
+				stored.replay(w)
+				return
+			}
+			// the original request failed and was forgotten so try again
+		}
+	}
+}
+
+// returns the response stored under scope, or a fresh one to be filled in
+// by the caller when there's none yet
+func (ctx *HandlerContext) storeResponse(scope idempotencyScope, fingerprint string) (*storedResponse, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	stored, ok := ctx.idempotency[scope]
+	if ok && !(stored.finished() && ctx.idempotencyExpired(stored)) {
+		return stored, true
+	}
+
+	stored = &storedResponse{
+		fingerprint: fingerprint,
+		storedAt:    ctx.now(),
+		done:        make(chan struct{}),
+	}
+	ctx.idempotency[scope] = stored
+	return stored, false
+}
+
+func (ctx *HandlerContext) record(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc, scope idempotencyScope, stored *storedResponse) {
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	handler(rec, r)
+
+	ctx.mu.Lock()
+	if rec.status >= http.StatusInternalServerError {
+		delete(ctx.idempotency, scope)
+	} else {
+		stored.storedAt = ctx.now()
+		stored.status = rec.status
+		stored.header = w.Header().Clone()
+		// a retry is a request of its own and keeps its own id
+		stored.header.Del("X-Request-ID")
+		stored.body = rec.body.Bytes()
+	}
+	ctx.mu.Unlock()
+	close(stored.done)
+}
+
+func (ctx *HandlerContext) idempotencyExpired(stored *storedResponse) bool {
+	return ctx.now().Sub(stored.storedAt) >= ctx.idempotencyWindow
+}
+
+// two requests are the same when they'd do the same thing: same endpoint,
+// same parameters and same body
+func fingerprintRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	now := time.Now()
+	ctx.now = func() time.Time { return now }
+	ctx.idempotencyWindow = time.Minute
+
+	t.Run("replays retried draw", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		url := fmt.Sprintf("/v1/decks/%s/draws?count=2", created.Guid)
+		first := sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "draw-1"}, "")
+		retry := sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "draw-1"}, "")
+
+		if retry.Body.String() != first.Body.String() || retry.Code != first.Code {
+			msg := "Expected retry to replay %v but found %v instead"
+			t.Errorf(msg, first.Body.String(), retry.Body.String())
+		}
+		if retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Expected retry to be marked as replayed")
+		}
+		if n := len(decks[created.Guid].Cards); n != 50 {
+			t.Errorf("Expected only 2 cards to be drawn, found %v left", n)
+		}
+	})
+
+	t.Run("replays retried create", func(t *testing.T) {
+		first := sendRequest(ctx, http.MethodPost, "/v1/decks?player=alice", map[string]string{"Idempotency-Key": "create-1"}, "")
+		retry := sendRequest(ctx, http.MethodPost, "/v1/decks?player=alice", map[string]string{"Idempotency-Key": "create-1"}, "")
+		if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+			t.Errorf("Expected the same deck to be returned, found %v", retry.Body.String())
+		}
+		if retry.Header().Get("Location") != first.Header().Get("Location") {
+			t.Errorf("Expected headers to be replayed too")
+		}
+		if id := retry.Header().Get("X-Request-ID"); id == "" || id == first.Header().Get("X-Request-ID") {
+			t.Errorf("Expected the retry to keep its own request id, found %q", id)
+		}
+	})
+
+	t.Run("rejects key reused with different parameters", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		url := fmt.Sprintf("/v1/decks/%s/draws", created.Guid)
+		sendRequest(ctx, http.MethodPost, url+"?count=1", map[string]string{"Idempotency-Key": "draw-2"}, "")
+		w := sendRequest(ctx, http.MethodPost, url+"?count=3", map[string]string{"Idempotency-Key": "draw-2"}, "")
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a reused key, found %v", w.Code)
+		}
+		if n := len(decks[created.Guid].Cards); n != 51 {
+			t.Errorf("Expected only the first draw to happen, found %v left", n)
+		}
+	})
+
+	t.Run("scopes keys to a deck", func(t *testing.T) {
+		first, _ := create(t, ctx, "/create")
+		second, _ := create(t, ctx, "/create")
+		for _, guid := range []uuid.UUID{first.Guid, second.Guid} {
+			url := fmt.Sprintf("/v1/decks/%s/draws", guid)
+			if w := sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "same-key"}, ""); w.Code != http.StatusOK {
+				t.Errorf("Expected the key to be usable on another deck, found %v", w.Code)
+			}
+		}
+	})
+
+	t.Run("forgets responses after the window", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		url := fmt.Sprintf("/v1/decks/%s/draws", created.Guid)
+		sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "draw-3"}, "")
+
+		now = now.Add(ctx.idempotencyWindow)
+		ctx.sweep()
+		w := sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "draw-3"}, "")
+		if w.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("Expected the draw to happen again once the window passed")
+		}
+		if n := len(decks[created.Guid].Cards); n != 50 {
+			t.Errorf("Expected 2 cards to be drawn, found %v left", n)
+		}
+	})
+
+	t.Run("draws once for concurrent retries", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		url := fmt.Sprintf("/v1/decks/%s/draws", created.Guid)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sendRequest(ctx, http.MethodPost, url, map[string]string{"Idempotency-Key": "draw-4"}, "")
+			}()
+		}
+		wg.Wait()
+
+		ctx.mu.Lock()
+		n := len(decks[created.Guid].Cards)
+		ctx.mu.Unlock()
+		if n != 51 {
+			t.Errorf("Expected a single card to be drawn, found %v left", n)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"example.com/game"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrDeckExpired  = errors.New("Deck has expired")
+	ErrGameExpired  = errors.New("Game has expired")
+	ErrTooManyDecks = errors.New("Too many decks")
+)
+
+// how long decks live and how many a single owner may keep around
+type Retention struct {
+	DefaultTTL       time.Duration
+	MaxTTL           time.Duration
+	ExhaustedTTL     time.Duration
+	TombstoneTTL     time.Duration
+	MaxDecksPerOwner int
+}
+
+func DefaultRetention() Retention {
+	return Retention{
+		DefaultTTL:       24 * time.Hour,
+		MaxTTL:           7 * 24 * time.Hour,
+		ExhaustedTTL:     10 * time.Minute,
+		TombstoneTTL:     24 * time.Hour,
+		MaxDecksPerOwner: 100,
+	}
+}
+
+// a deck is evicted once nobody touched it for ttl, or sooner once there are
+// no cards left to draw from it. Game sessions are leased the same way and
+// evicted sooner once finished
+type lease struct {
+	ttl        time.Duration
+	lastAccess time.Time
+}
+
+func (ctx *HandlerContext) lease(guid uuid.UUID, ttl time.Duration) {
+	ctx.leases[guid] = &lease{ttl, ctx.now()}
+}
+
+func (ctx *HandlerContext) touch(guid uuid.UUID) {
+	if l, ok := ctx.leases[guid]; ok {
+		l.lastAccess = ctx.now()
+	}
+}
+
+// decks and games that were evicted are remembered for a while so clients can be told
+// they are gone rather than that they never existed
+func (ctx *HandlerContext) expired(guid uuid.UUID) bool {
+	_, ok := ctx.tombstones[guid]
+	return ok
+}
+
+func retrieveStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrDeckExpired), errors.Is(err, ErrGameExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrDeckNotFound), errors.Is(err, ErrGameNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// ?ttl=30m is capped by MaxTTL
+func (ctx *HandlerContext) requestedTTL(r *http.Request) (time.Duration, error) {
+	return ctx.parseTTL(r.URL.Query().Get("ttl"))
+}
+
+func (ctx *HandlerContext) parseTTL(param string) (time.Duration, error) {
+	if param == "" {
+		return ctx.retention.DefaultTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(param)
+	if err != nil || ttl <= 0 {
+		return 0, &InputError{"ttl", param, ErrInvalidParameter}
+	}
+
+	if ttl > ctx.retention.MaxTTL {
+		ttl = ctx.retention.MaxTTL
+	}
+	return ttl, nil
+}
+
+// decks without an owner can't be attributed to anyone so they don't count
+func (ctx *HandlerContext) checkQuota(owner string) error {
+	if owner == "" {
+		return nil
+	}
+
+	owned := 0
+	for _, d := range *ctx.decks {
+		if d.Owner == owner {
+			owned += 1
+		}
+	}
+
+	if owned >= ctx.retention.MaxDecksPerOwner {
+		return fmt.Errorf("%w: %v already has %d decks", ErrTooManyDecks, owner, owned)
+	}
+	return nil
+}
+
+// must be called holding ctx.mu
+func (ctx *HandlerContext) sweep() {
+	now := ctx.now()
+	for guid, d := range *ctx.decks {
+		l, ok := ctx.leases[guid]
+		if !ok {
+			// decks that predate leases (i.e. restored) start one right away
+			ctx.lease(guid, ctx.retention.DefaultTTL)
+			continue
+		}
+
+		idle := now.Sub(l.lastAccess)
+		switch {
+		case idle >= l.ttl:
+			ctx.evict(guid, "idle")
+		case len(d.Cards) == 0 && idle >= ctx.retention.ExhaustedTTL:
+			ctx.evict(guid, "exhausted")
+		}
+	}
+
+	for guid, session := range *ctx.sessions {
+		l, ok := ctx.leases[guid]
+		if !ok {
+			ctx.lease(guid, ctx.retention.DefaultTTL)
+			continue
+		}
+
+		idle := now.Sub(l.lastAccess)
+		switch {
+		case idle >= l.ttl:
+			ctx.evictGame(guid, "idle")
+		case session.State.Phase == game.Finished && idle >= ctx.retention.ExhaustedTTL:
+			ctx.evictGame(guid, "finished")
+		}
+	}
+
+	for guid, evictedAt := range ctx.tombstones {
+		if now.Sub(evictedAt) >= ctx.retention.TombstoneTTL {
+			delete(ctx.tombstones, guid)
+		}
+	}
+
+	for scope, stored := range ctx.idempotency {
+		if stored.finished() && ctx.idempotencyExpired(stored) {
+			delete(ctx.idempotency, scope)
+		}
+	}
+
+	ctx.limiter.sweep(ctx.limits, now)
+}
+
+func (ctx *HandlerContext) evict(guid uuid.UUID, reason string) {
+	delete(*ctx.decks, guid)
+	delete(ctx.leases, guid)
+	ctx.tombstones[guid] = ctx.now()
+	ctx.broker.Forget(guid)
+	ctx.metrics.evictedDeck(reason)
+}
+
+func (ctx *HandlerContext) evictGame(guid uuid.UUID, reason string) {
+	delete(*ctx.sessions, guid)
+	delete(ctx.leases, guid)
+	ctx.tombstones[guid] = ctx.now()
+	ctx.broker.Forget(guid)
+	ctx.metrics.evictedGame(reason)
+}
+
+// sweeps every interval until stop is called
+func (ctx *HandlerContext) StartJanitor(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				ctx.mu.Lock()
+				ctx.sweep()
+				ctx.mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"example.com/game"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestJanitor(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	now := time.Now()
+	ctx.now = func() time.Time { return now }
+
+	t.Run("evicts idle decks", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?ttl=1h")
+		now = now.Add(59 * time.Minute)
+		ctx.sweep()
+		if _, ok := decks[created.Guid]; !ok {
+			t.Fatalf("Expected deck not to be evicted before its ttl")
+		}
+
+		// opening the deck keeps it alive
+		open(t, ctx, fmt.Sprintf("/open/%s", created.Guid))
+		now = now.Add(59 * time.Minute)
+		ctx.sweep()
+		if _, ok := decks[created.Guid]; !ok {
+			t.Fatalf("Expected deck to have been kept alive by opening it")
+		}
+
+		now = now.Add(time.Hour)
+		before := evictionCount(ctx, "idle")
+		ctx.sweep()
+		if _, ok := decks[created.Guid]; ok {
+			t.Errorf("Expected idle deck to have been evicted")
+		}
+
+		if evictionCount(ctx, "idle") != before+1 {
+			t.Errorf("Expected idle eviction to have been counted")
+		}
+	})
+
+	t.Run("evicts exhausted decks sooner", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?cards=AS,KH")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2", created.Guid))
+		now = now.Add(ctx.retention.ExhaustedTTL)
+		ctx.sweep()
+		if _, ok := decks[created.Guid]; ok {
+			t.Errorf("Expected exhausted deck to have been evicted")
+		}
+	})
+
+	t.Run("expired deck is gone", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?ttl=1m")
+		now = now.Add(time.Minute)
+		ctx.sweep()
+
+		w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/open/%s", created.Guid))
+		if w.Code != http.StatusGone {
+			t.Errorf("Expected 410 opening an expired deck, found %v", w.Code)
+		}
+
+		now = now.Add(ctx.retention.TombstoneTTL)
+		ctx.sweep()
+		w = fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/open/%s", created.Guid))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected expired deck to have been forgotten, found %v", w.Code)
+		}
+	})
+
+	t.Run("evicts idle and finished games", func(t *testing.T) {
+		idle, _ := playGame(t, ctx, http.MethodPost, "/v1/games/highcard?players=alice,bob", "")
+		finished, _ := playGame(t, ctx, http.MethodPost, "/v1/games/highcard?players=alice,bob", "")
+		(*ctx.sessions)[finished.Guid].State.Phase = game.Finished
+
+		now = now.Add(ctx.retention.ExhaustedTTL)
+		ctx.sweep()
+		if _, ok := (*ctx.sessions)[finished.Guid]; ok {
+			t.Errorf("Expected the finished game to have been evicted")
+		}
+		if _, ok := (*ctx.sessions)[idle.Guid]; !ok {
+			t.Fatalf("Expected the game in progress to be kept until it's idle")
+		}
+
+		now = now.Add(ctx.retention.DefaultTTL)
+		ctx.sweep()
+		w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/v1/games/highcard/%s?player=alice", idle.Guid))
+		if w.Code != http.StatusGone || ctx.metrics.gameEvictions["idle"] != 1 || ctx.metrics.gameEvictions["finished"] != 1 {
+			t.Errorf("Expected 410 for the idle game and both evictions counted, found %v %v", w.Code, ctx.metrics.gameEvictions)
+		}
+	})
+
+	t.Run("caps requested ttl", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?ttl=10000h")
+		if ctx.leases[created.Guid].ttl != ctx.retention.MaxTTL {
+			t.Errorf("Expected ttl to be capped, found %v", ctx.leases[created.Guid].ttl)
+		}
+	})
+
+	t.Run("rejects invalid ttl", func(t *testing.T) {
+		if _, err := create(t, ctx, "/create?ttl=forever"); err == nil {
+			t.Errorf("Expected err due to invalid ttl")
+		}
+	})
+
+	t.Run("enforces quota per owner", func(t *testing.T) {
+		ctx.retention.MaxDecksPerOwner = 2
+		create(t, ctx, "/create?player=alice")
+		create(t, ctx, "/create?player=alice")
+
+		w := fetchResponse(ctx, http.MethodPost, "/create?player=alice")
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusConflict || problem.Code != "too_many_decks" || w.Header().Get("Retry-After") != "" {
+			t.Errorf("Expected alice to be over quota, found %v %v", w.Code, w.Body)
+		}
+
+		if _, err := create(t, ctx, "/create?player=bob"); err != nil {
+			t.Errorf("Expected bob not to be affected by alice's quota")
+		}
+	})
+}
+
+func evictionCount(ctx *HandlerContext, reason string) int {
+	ctx.metrics.mu.Lock()
+	defer ctx.metrics.mu.Unlock()
+	return ctx.metrics.deckEvictions[reason]
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"encoding/json"
 	"example.com/deck"
+	"example.com/game"
 	"github.com/google/uuid"
 )
 
@@ -24,35 +24,77 @@ func intoCreatedDeck(deck deck.Deck) CreatedDeck {
 	}
 }
 
-func (d *CreatedDeck) toJson() (string, error) {
-	jsonBytes, err := json.Marshal(d)
-	if err != nil {
-		return "", err
+// only what player is allowed to see makes it into the open deck. The number
+// of cards left in the deck is always public (one can see how tall it is)
+func intoOpenDeck(d deck.Deck, player string) OpenDeck {
+	Guid := d.Guid
+	IsShuffled := d.IsShuffled()
+	RemainingCardCount := d.RemainingCardCount()
+	Owner := d.Owner
+	SharedWith := d.SharedWith
+	Visibility := d.Visibility.String()
+	Cards := []OpenCard{}
+	if d.FacesVisibleTo(player) {
+		Cards = IntoOpenCards(d.Cards)
 	}
-	return string(jsonBytes), nil
-}
-
-func intoOpenDeck(deck deck.Deck) OpenDeck {
-	Guid := deck.Guid
-	IsShuffled := deck.IsShuffled()
-	RemainingCardCount := deck.RemainingCardCount()
-	Cards := IntoOpenCards(deck.Cards)
+	Piles := intoOpenPiles(d.Piles, player)
 
 	return OpenDeck{
 		Guid,
 		IsShuffled,
 		RemainingCardCount,
+		Owner,
+		SharedWith,
+		Visibility,
 		Cards,
+		Piles,
+		d.Version,
 	}
 }
 
 type OpenDeck struct {
-	Guid               uuid.UUID  `json:"deck_id"`
-	IsShuffled         bool       `json:"shuffled"`
-	RemainingCardCount int        `json:"remaining"`
-	Cards              []OpenCard `json:"cards"`
+	Guid               uuid.UUID           `json:"deck_id"`
+	IsShuffled         bool                `json:"shuffled"`
+	RemainingCardCount int                 `json:"remaining"`
+	Owner              string              `json:"owner,omitempty"`
+	SharedWith         []string            `json:"shared_with,omitempty"`
+	Visibility         string              `json:"visibility"`
+	Cards              []OpenCard          `json:"cards,omitempty"`
+	Piles              map[string]OpenPile `json:"piles,omitempty"`
+	Version            int                 `json:"version"`
+}
+
+type OpenPile struct {
+	Owner      string     `json:"owner,omitempty"`
+	Visibility string     `json:"visibility"`
+	Count      *int       `json:"count,omitempty"`
+	Cards      []OpenCard `json:"cards,omitempty"`
+}
+
+func intoOpenPiles(piles map[string]deck.Pile, player string) map[string]OpenPile {
+	openPiles := map[string]OpenPile{}
+	for name, pile := range piles {
+		openPiles[name] = intoOpenPile(pile.ViewFor(player))
+	}
+	return openPiles
+}
+
+func intoOpenPile(view deck.PileView) OpenPile {
+	var cards []OpenCard
+	if view.Cards != nil {
+		cards = IntoOpenCards(view.Cards)
+	}
+	return OpenPile{
+		view.Owner,
+		view.Visibility.String(),
+		view.Count,
+		cards,
+	}
 }
 
+// cards as drawn, in the order they were
+type OpenCards []OpenCard
+
 type OpenCard struct {
 	Rank string `json:"value"`
 	Suit string `json:"suit"`
@@ -78,20 +120,37 @@ func intoOpenCard(card deck.Card) OpenCard {
 	}
 }
 
-// some generics would come in handy here to avoid repeating the JSON
-// marshalling logic yet again
-func (d *OpenDeck) toJson() (string, error) {
-	jsonBytes, err := json.Marshal(d)
-	if err != nil {
-		return "", err
-	}
-	return string(jsonBytes), nil
+type OpenView struct {
+	Guid      uuid.UUID             `json:"game_id"`
+	Type      string                `json:"type"`
+	Player    game.PlayerID         `json:"player"`
+	Players   []game.PlayerID       `json:"players"`
+	Turn      game.PlayerID         `json:"turn"`
+	Phase     game.Phase            `json:"phase"`
+	Piles     map[string]OpenPile   `json:"piles"`
+	Remaining int                   `json:"remaining"`
+	Legal     []game.Action         `json:"legal_actions"`
+	Scores    map[game.PlayerID]int `json:"scores,omitempty"`
+	Extra     any                   `json:"extra,omitempty"`
 }
 
-func marshallOpenCardsToJson(cards []OpenCard) (string, error) {
-	jsonBytes, err := json.Marshal(cards)
-	if err != nil {
-		return "", err
+func intoOpenView(session *game.Session, view game.View) OpenView {
+	piles := map[string]OpenPile{}
+	for name, pile := range view.Piles {
+		piles[name] = intoOpenPile(pile)
+	}
+
+	return OpenView{
+		Guid:      session.Guid,
+		Type:      session.Type,
+		Player:    view.Player,
+		Players:   view.Players,
+		Turn:      view.Turn,
+		Phase:     view.Phase,
+		Piles:     piles,
+		Remaining: view.Remaining,
+		Legal:     view.Legal,
+		Scores:    view.Scores,
+		Extra:     view.Extra,
 	}
-	return string(jsonBytes), nil
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited  = errors.New("Too many requests")
+	ErrDeckTooLarge = errors.New("Deck too large")
+)
+
+// what a single client may ask of the server so that nobody can exhaust
+// it, i.e. by creating decks in a loop
+type Limits struct {
+	// requests per second a client may make, 0 for no limit, and how many
+	// it may make at once
+	Rate  float64
+	Burst int
+	// requests per second per operation (as in Create), instead of Rate
+	Endpoints map[string]float64
+	// cards in a custom or imported deck
+	MaxDeckSize int
+	// bytes in a request body
+	MaxBodySize int64
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		Rate:        0,
+		Burst:       20,
+		Endpoints:   map[string]float64{},
+		MaxDeckSize: 520,
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// the rate an operation is limited to, 0 for no limit
+func (l *Limits) rate(operation string) float64 {
+	if rate, ok := l.Endpoints[operation]; ok {
+		return rate
+	}
+	return l.Rate
+}
+
+// operations with their own limit get their own buckets, the others share
+// one per client
+type bucketKey struct {
+	operation string
+	client    string
+}
+
+// holds up to burst tokens, refilled at rate per second. A request takes one
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: map[bucketKey]*bucket{}}
+}
+
+// takes a token from the bucket of key, or says how long until there is one
+func (l *RateLimiter) take(key bucketKey, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens -= 1
+	return true, 0
+}
+
+// forgets buckets that would be full by now, which are no different from
+// buckets that were never there
+func (l *RateLimiter) sweep(limits Limits, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		rate := limits.rate(key.operation)
+		if rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*rate >= float64(limits.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// the address a request came from, which is all there is to count it
+// against before it's authenticated
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// answers 429 with Retry-After to addresses above their rate for operation,
+// and keeps request bodies within MaxBodySize. Comes before authentication
+// so that guessing keys or tokens is throttled like anything else
+func (ctx *HandlerContext) throttled(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, ctx.limits.MaxBodySize)
+		}
+		if err := ctx.throttle(operation, "ip:"+remoteHost(r.RemoteAddr)); err != nil {
+			writeRateLimited(w, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// the same for whoever the API key or token says the request is from, so
+// that calling from several addresses doesn't get anyone more
+func (ctx *HandlerContext) limited(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := r.Context().Value(principalKey{}).(string); ok {
+			if err := ctx.throttle(operation, "principal:"+principal); err != nil {
+				writeRateLimited(w, err)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// takes a token for client, or says how long until there is one. Operations
+// with their own limit get their own buckets
+func (ctx *HandlerContext) throttle(operation string, client string) *RateLimitedError {
+	rate := ctx.limits.rate(operation)
+	if rate <= 0 {
+		return nil
+	}
+
+	key := bucketKey{client: client}
+	if _, ok := ctx.limits.Endpoints[operation]; ok {
+		key.operation = operation
+	}
+	if ok, wait := ctx.limiter.take(key, rate, ctx.limits.Burst, ctx.now()); !ok {
+		return &RateLimitedError{operation, rate, wait}
+	}
+	return nil
+}
+
+type RateLimitedError struct {
+	Operation string
+	Rate      float64
+	Wait      time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%v: %v may be called %v times per second, try again in %v", ErrRateLimited, e.Operation, e.Rate, e.Wait.Round(time.Millisecond))
+}
+
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// in whole seconds, at least one
+func (e *RateLimitedError) retryAfter() int {
+	return max(int(math.Ceil(e.Wait.Seconds())), 1)
+}
+
+func writeRateLimited(w http.ResponseWriter, err *RateLimitedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(err.retryAfter()))
+	writeError(w, http.StatusTooManyRequests, err)
+}
+
+// 413 when the body was cut off for being too large
+func bodyStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// custom decks are counted before their cards are parsed so that huge
+// lists are refused cheaply
+func (ctx *HandlerContext) checkDeckSize(cards int) error {
+	if cards > ctx.limits.MaxDeckSize {
+		return fmt.Errorf("%w: a deck may have at most %d cards, found %d", ErrDeckTooLarge, ctx.limits.MaxDeckSize, cards)
+	}
+	return nil
+}
+
+// as in AS,KD for /create?cards=
+func countCodes(codes string) int {
+	if codes == "" {
+		return 0
+	}
+	return strings.Count(codes, ",") + 1
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimits(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx.now = func() time.Time { return now }
+
+	request := func(method string, url string, ip string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, nil)
+		r.RemoteAddr = ip + ":4242"
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		ctx.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("limits every client to the rate", func(t *testing.T) {
+		ctx.limits = Limits{Rate: 1, Burst: 2, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+		ctx.limiter = NewRateLimiter()
+
+		for i := 0; i < 2; i++ {
+			if w := request(http.MethodPost, "/v1/decks", "192.0.2.1", nil); w.Code != http.StatusCreated {
+				t.Fatalf("Expected the burst to be allowed, found %v", w.Code)
+			}
+		}
+		w := request(http.MethodGet, "/v1/decks/"+uuid.NewString(), "192.0.2.1", nil)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+			t.Fatalf("Expected 429 with Retry-After, found %v %v", w.Code, w.Header())
+		}
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if problem.Code != "rate_limited" {
+			t.Errorf("Expected rate_limited, found %v", problem.Code)
+		}
+
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.2", nil); w.Code != http.StatusCreated {
+			t.Errorf("Expected another client to be allowed, found %v", w.Code)
+		}
+		now = now.Add(time.Second)
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.1", nil); w.Code != http.StatusCreated {
+			t.Errorf("Expected a token to be back after a second, found %v", w.Code)
+		}
+	})
+
+	t.Run("counts API keys as well as addresses", func(t *testing.T) {
+		ctx.limits = Limits{Rate: 1, Burst: 1, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+		ctx.limiter = NewRateLimiter()
+		ctx.auth = NewAuthenticator(nil, map[string]string{"alices-key": "alice", "bobs-key": "bob"})
+		defer func() { ctx.auth = nil }()
+
+		alice := map[string]string{"X-API-Key": "alices-key"}
+		bob := map[string]string{"X-API-Key": "bobs-key"}
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.1", alice); w.Code != http.StatusCreated {
+			t.Fatalf("Expected alice to be allowed, found %v", w.Code)
+		}
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.1", bob); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected bob to be limited from the same address, found %v", w.Code)
+		}
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.3", alice); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected alice to be limited from another address, found %v", w.Code)
+		}
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.4", bob); w.Code != http.StatusCreated {
+			t.Errorf("Expected bob to be allowed from another address, found %v", w.Code)
+		}
+	})
+
+	t.Run("throttles guessing keys", func(t *testing.T) {
+		ctx.limits = Limits{Rate: 1, Burst: 2, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+		ctx.limiter = NewRateLimiter()
+		ctx.auth = NewAuthenticator([]byte("s3cr3t"), map[string]string{"alices-key": "alice"})
+		defer func() { ctx.auth = nil }()
+
+		guesses := []map[string]string{{"X-API-Key": "guess"}, {"Authorization": "Bearer guess"}}
+		for _, guess := range guesses {
+			if w := request(http.MethodPost, "/v1/decks", "192.0.2.5", guess); w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected %v to be refused, found %v", guess, w.Code)
+			}
+		}
+		alice := map[string]string{"X-API-Key": "alices-key"}
+		if w := request(http.MethodPost, "/v1/decks", "192.0.2.5", alice); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected the address to be limited before its keys are looked at, found %v", w.Code)
+		}
+	})
+
+	t.Run("limits operations on their own", func(t *testing.T) {
+		ctx.limits = Limits{Burst: 1, Endpoints: map[string]float64{"Create": 0.5}, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+		ctx.limiter = NewRateLimiter()
+
+		w := request(http.MethodPost, "/v1/decks", "192.0.2.1", nil)
+		created := decodeCreatedDeck(t, w.Body.String())
+		if w := request(http.MethodPost, "/create", "192.0.2.1", nil); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+			t.Errorf("Expected Create to be limited whatever its path, found %v %v", w.Code, w.Header().Get("Retry-After"))
+		}
+		for i := 0; i < 3; i++ {
+			if w := request(http.MethodGet, "/v1/decks/"+created.Guid.String(), "192.0.2.1", nil); w.Code != http.StatusOK {
+				t.Errorf("Expected Open not to be limited, found %v", w.Code)
+			}
+		}
+	})
+
+	t.Run("forgets clients whose buckets are full again", func(t *testing.T) {
+		ctx.limits = Limits{Rate: 1, Burst: 2, MaxDeckSize: 52, MaxBodySize: 1 << 10}
+		ctx.limiter = NewRateLimiter()
+		request(http.MethodGet, "/v1/decks/"+uuid.NewString(), "192.0.2.1", nil)
+
+		ctx.limiter.sweep(ctx.limits, now)
+		if len(ctx.limiter.buckets) != 1 {
+			t.Errorf("Expected the bucket to be kept while refilling, found %v", ctx.limiter.buckets)
+		}
+		ctx.limiter.sweep(ctx.limits, now.Add(time.Second))
+		if len(ctx.limiter.buckets) != 0 {
+			t.Errorf("Expected the full bucket to be forgotten, found %v", ctx.limiter.buckets)
+		}
+	})
+}
+
+func TestSizeLimits(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.limits.MaxDeckSize = 3
+	ctx.limits.MaxBodySize = 64
+
+	t.Run("refuses decks with too many cards", func(t *testing.T) {
+		w := fetchResponse(ctx, http.MethodPost, "/v1/decks?cards=AS,KD,QH,JC")
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusBadRequest || problem.Code != "deck_too_large" {
+			t.Errorf("Expected deck_too_large, found %v %v", w.Code, w.Body)
+		}
+		if w := fetchResponse(ctx, http.MethodPost, "/v1/decks?cards=AS,KD,QH"); w.Code != http.StatusCreated {
+			t.Errorf("Expected a deck as large as allowed, found %v", w.Code)
+		}
+	})
+
+	t.Run("refuses bodies that are too large", func(t *testing.T) {
+		for _, headers := range []map[string]string{{}, {"Idempotency-Key": "k1"}} {
+			r := httptest.NewRequest(http.MethodPost, "/v1/imports", strings.NewReader(strings.Repeat("x", 65)))
+			for name, value := range headers {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			ctx.Routes().ServeHTTP(w, r)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected 413 with %v, found %v %v", headers, w.Code, w.Body)
+			}
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// query parameters that may give away hidden cards (the cards a deck is
+// made of) or secrets, which are only logged as being there
+var redactedParams = []string{"cards", "access_token"}
+
+// a logger writing format (json or text) to w from level on
+func NewLogger(w io.Writer, format string, level string) *slog.Logger {
+	var l slog.Level
+	l.UnmarshalText([]byte(level))
+	options := &slog.HandlerOptions{Level: l}
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+type requestLogKey struct{}
+
+// what handlers add to the log of the request they answer
+type requestLog struct {
+	attrs []slog.Attr
+}
+
+// adds attrs to the log of r, i.e. the guid of a deck that was just created.
+// Never cards: whoever reads the logs may not see them
+func annotate(r *http.Request, attrs ...slog.Attr) {
+	if entry, ok := r.Context().Value(requestLogKey{}).(*requestLog); ok {
+		entry.attrs = append(entry.attrs, attrs...)
+	}
+}
+
+// the X-Request-ID of r if it's a sensible one, a new one otherwise
+func requestID(r *http.Request) string {
+	return sensibleRequestID(r.Header.Get("X-Request-ID"))
+}
+
+// id unless it's empty, too long or has more than printable ASCII in it
+func sensibleRequestID(id string) string {
+	if id == "" || len(id) > 128 || strings.ContainsFunc(id, func(c rune) bool { return c <= ' ' || c > '~' }) {
+		return uuid.NewString()
+	}
+	return id
+}
+
+// tags every request with an X-Request-ID, sent back in the response, and
+// logs it once answered as operation name. Requests are traced too when
+// spans are exported
+func (ctx *HandlerContext) logged(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := requestID(r)
+		w.Header().Set("X-Request-ID", id)
+
+		entry := &requestLog{}
+		span := ctx.tracer.start(r)
+		r = r.WithContext(context.WithValue(r.Context(), requestLogKey{}, entry))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		latency := time.Since(start)
+
+		attrs := []slog.Attr{
+			slog.String("request_id", id),
+			slog.String("operation", name),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		}
+		if query := loggedQuery(r); len(query) > 0 {
+			attrs = append(attrs, slog.Any("query", slog.GroupValue(query...)))
+		}
+		if resource := r.PathValue("id"); resource != "" {
+			if gameType := r.PathValue("type"); gameType != "" {
+				attrs = append(attrs, slog.String("game_type", gameType), slog.String("game", resource))
+			} else {
+				attrs = append(attrs, slog.String("deck", resource))
+			}
+		}
+		attrs = append(attrs, entry.attrs...)
+		attrs = append(attrs, slog.Int("status", rec.status), slog.Duration("latency", latency))
+		if span != nil {
+			ctx.tracer.end(span, name, id, rec.status, entry.attrs)
+			attrs = append(attrs, slog.String("trace_id", span.SpanContext.TraceID), slog.String("span_id", span.SpanContext.SpanID))
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		ctx.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	}
+}
+
+// the query of r, sorted, with whatever may give away cards redacted
+func loggedQuery(r *http.Request) []slog.Attr {
+	query := r.URL.Query()
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	attrs := []slog.Attr{}
+	for _, key := range keys {
+		value := strings.Join(query[key], ",")
+		if slices.Contains(redactedParams, strings.ToLower(key)) {
+			value = "[redacted]"
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return attrs
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	var logs, spans bytes.Buffer
+	ctx.logger = NewLogger(&logs, "json", "info")
+	ctx.tracer = NewTracer(&spans)
+
+	request := func(method string, url string, headers map[string]string) (*httptest.ResponseRecorder, map[string]any) {
+		logs.Reset()
+		r := httptest.NewRequest(method, url, nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		ctx.Routes().ServeHTTP(w, r)
+
+		var entry map[string]any
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("Expected a single JSON log line, found %q: %v", logs.String(), err)
+		}
+		return w, entry
+	}
+
+	w, entry := request(http.MethodPost, "/v1/decks?cards=AS,KD,QH&visibility=hidden", nil)
+	created := decodeCreatedDeck(t, w.Body.String())
+
+	t.Run("logs the operation, deck and latency", func(t *testing.T) {
+		expected := map[string]any{
+			"msg":       "request",
+			"operation": "Create",
+			"method":    "POST",
+			"path":      "/v1/decks",
+			"deck":      created.Guid.String(),
+			"status":    float64(http.StatusCreated),
+		}
+		for key, value := range expected {
+			if entry[key] != value {
+				t.Errorf("Expected %v to be %v, found %v", key, value, entry[key])
+			}
+		}
+		if _, ok := entry["latency"].(float64); !ok {
+			t.Errorf("Expected a latency, found %v", entry["latency"])
+		}
+	})
+
+	t.Run("keeps the cards out of the logs", func(t *testing.T) {
+		if strings.Contains(logs.String(), "AS") || strings.Contains(logs.String(), "KD") {
+			t.Errorf("Expected the cards to be redacted, found %v", logs.String())
+		}
+		if query, _ := entry["query"].(map[string]any); query["cards"] != "[redacted]" || query["visibility"] != "hidden" {
+			t.Errorf("Expected only the cards to be redacted, found %v", entry["query"])
+		}
+
+		_, entry := request(http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?count=2", created.Guid), nil)
+		if entry["drawn"] != float64(2) || strings.Contains(logs.String(), "QH") {
+			t.Errorf("Expected how many cards were drawn but not which, found %v", logs.String())
+		}
+	})
+
+	t.Run("tags requests with an id", func(t *testing.T) {
+		w, entry := request(http.MethodGet, "/v1/decks/"+created.Guid.String(), nil)
+		generated := w.Header().Get("X-Request-ID")
+		if _, err := uuid.Parse(generated); err != nil || entry["request_id"] != generated {
+			t.Errorf("Expected a new request id, found %q and %v", generated, entry["request_id"])
+		}
+
+		w, entry = request(http.MethodGet, "/v1/decks/"+created.Guid.String(), map[string]string{"X-Request-ID": "abc-123"})
+		if w.Header().Get("X-Request-ID") != "abc-123" || entry["request_id"] != "abc-123" {
+			t.Errorf("Expected the given request id, found %q and %v", w.Header().Get("X-Request-ID"), entry["request_id"])
+		}
+
+		w, _ = request(http.MethodGet, "/v1/decks/"+created.Guid.String(), map[string]string{"X-Request-ID": "evil\x7f"})
+		if w.Header().Get("X-Request-ID") == "evil\x7f" {
+			t.Errorf("Expected a request id with control characters to be replaced")
+		}
+	})
+
+	t.Run("exports spans continuing the caller's trace", func(t *testing.T) {
+		spans.Reset()
+		parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		_, entry := request(http.MethodGet, "/v1/decks/"+created.Guid.String(), map[string]string{"traceparent": parent})
+
+		var s span
+		if err := json.Unmarshal(spans.Bytes(), &s); err != nil {
+			t.Fatalf("Expected a span, found %q: %v", spans.String(), err)
+		}
+		if s.Name != "GET /v1/decks/{id}" || s.SpanKind != spanKindServer {
+			t.Errorf("Expected a server span named after the route, found %v %v", s.Name, s.SpanKind)
+		}
+		if s.SpanContext.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || s.Parent.SpanID != "00f067aa0ba902b7" || !s.Parent.Remote {
+			t.Errorf("Expected the trace to be continued, found %+v", s)
+		}
+		if entry["trace_id"] != s.SpanContext.TraceID || entry["span_id"] != s.SpanContext.SpanID {
+			t.Errorf("Expected the log to point at the span, found %v", entry)
+		}
+
+		attributes := map[string]any{}
+		for _, attribute := range s.Attributes {
+			attributes[attribute.Key] = attribute.Value.Value
+		}
+		if attributes["http.route"] != "/v1/decks/{id}" || attributes["http.response.status_code"] != float64(http.StatusOK) || attributes["cards.operation"] != "Open" {
+			t.Errorf("Expected the route, status and operation, found %v", attributes)
+		}
+	})
+
+	t.Run("starts a trace without a valid traceparent", func(t *testing.T) {
+		spans.Reset()
+		request(http.MethodGet, "/v1/decks/"+created.Guid.String(), map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"})
+		var s span
+		json.Unmarshal(spans.Bytes(), &s)
+		if len(s.SpanContext.TraceID) != 32 || strings.Trim(s.SpanContext.TraceID, "0") == "" || s.Parent.SpanID != "" {
+			t.Errorf("Expected a new trace, found %+v", s)
+		}
+	})
+}
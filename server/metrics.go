@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upper bounds of the latency buckets in seconds, as Prometheus clients
+// have them by default
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// what the engine counts as it goes. What can be counted when asked (decks,
+// games) is counted at scrape time instead
+type Metrics struct {
+	mu          sync.Mutex
+	requests    map[requestLabels]int
+	latencies   map[string]*histogram
+	cardsDrawn  int
+	shuffles    int
+	storeErrors int
+	// per reason, as in idle
+	deckEvictions map[string]int
+	gameEvictions map[string]int
+}
+
+type requestLabels struct {
+	handler string
+	method  string
+	code    int
+}
+
+type histogram struct {
+	// counts per bucket, not cumulative
+	counts []int
+	sum    float64
+	count  int
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:      map[requestLabels]int{},
+		latencies:     map[string]*histogram{},
+		deckEvictions: map[string]int{},
+		gameEvictions: map[string]int{},
+	}
+}
+
+func (m *Metrics) observe(handler string, method string, code int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestLabels{handler, method, code}] += 1
+	h, ok := m.latencies[handler]
+	if !ok {
+		h = &histogram{counts: make([]int, len(latencyBuckets))}
+		m.latencies[handler] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i] += 1
+			break
+		}
+	}
+	h.sum += seconds
+	h.count += 1
+}
+
+func (m *Metrics) drew(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cardsDrawn += count
+}
+
+func (m *Metrics) shuffled() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shuffles += 1
+}
+
+func (m *Metrics) storeFailed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storeErrors += 1
+}
+
+func (m *Metrics) evictedDeck(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deckEvictions[reason] += 1
+}
+
+func (m *Metrics) evictedGame(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gameEvictions[reason] += 1
+}
+
+// counts the requests of next and how long they took under name
+func (ctx *HandlerContext) measure(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		ctx.metrics.observe(name, r.Method, rec.status, time.Since(start))
+	}
+}
+
+// as in Create for ctx.Create
+func handlerName(handler http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// only keeps the status. Streams of events need to flush, WebSockets to
+// take over the connection and both to set deadlines
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("The connection cannot be taken over")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// GET /metrics in the Prometheus text format
+func (ctx *HandlerContext) Metrics(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	decks := len(*ctx.decks)
+	games := map[string]int{}
+	for _, session := range *ctx.sessions {
+		games[session.Type] += 1
+	}
+	ctx.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ctx.metrics.write(w, decks, games)
+}
+
+func (m *Metrics) write(w io.Writer, decks int, games map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metric(w, "cards_http_requests_total", "counter", "Requests answered per handler, method and status code")
+	requests := []string{}
+	for labels, count := range m.requests {
+		requests = append(requests, fmt.Sprintf("cards_http_requests_total{handler=%q,method=%q,code=\"%d\"} %d",
+			labels.handler, labels.method, labels.code, count))
+	}
+	sort.Strings(requests)
+	for _, line := range requests {
+		fmt.Fprintln(w, line)
+	}
+
+	metric(w, "cards_http_request_duration_seconds", "histogram", "How long handlers took to answer")
+	handlers := []string{}
+	for handler := range m.latencies {
+		handlers = append(handlers, handler)
+	}
+	sort.Strings(handlers)
+	for _, handler := range handlers {
+		h := m.latencies[handler]
+		cumulative := 0
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(w, "cards_http_request_duration_seconds_bucket{handler=%q,le=%q} %d\n", handler, le, cumulative)
+		}
+		fmt.Fprintf(w, "cards_http_request_duration_seconds_bucket{handler=%q,le=\"+Inf\"} %d\n", handler, h.count)
+		fmt.Fprintf(w, "cards_http_request_duration_seconds_sum{handler=%q} %v\n", handler, h.sum)
+		fmt.Fprintf(w, "cards_http_request_duration_seconds_count{handler=%q} %d\n", handler, h.count)
+	}
+
+	metric(w, "cards_decks", "gauge", "Decks currently kept")
+	fmt.Fprintf(w, "cards_decks %d\n", decks)
+	metric(w, "cards_cards_drawn_total", "counter", "Cards drawn from every deck")
+	fmt.Fprintf(w, "cards_cards_drawn_total %d\n", m.cardsDrawn)
+	metric(w, "cards_shuffles_total", "counter", "Shuffles performed")
+	fmt.Fprintf(w, "cards_shuffles_total %d\n", m.shuffles)
+	metric(w, "cards_store_errors_total", "counter", "Failures to load or save decks")
+	fmt.Fprintf(w, "cards_store_errors_total %d\n", m.storeErrors)
+
+	metric(w, "cards_game_sessions", "gauge", "Games being played per type")
+	types := []string{}
+	for gameType := range games {
+		types = append(types, gameType)
+	}
+	sort.Strings(types)
+	for _, gameType := range types {
+		fmt.Fprintf(w, "cards_game_sessions{type=%q} %d\n", gameType, games[gameType])
+	}
+
+	metric(w, "cards_deck_evictions_total", "counter", "Decks evicted per reason")
+	perReason(w, "cards_deck_evictions_total", m.deckEvictions)
+	metric(w, "cards_game_evictions_total", "counter", "Game sessions evicted per reason")
+	perReason(w, "cards_game_evictions_total", m.gameEvictions)
+}
+
+func perReason(w io.Writer, name string, counts map[string]int) {
+	reasons := []string{}
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "%v{reason=%q} %d\n", name, reason, counts[reason])
+	}
+}
+
+func metric(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	created := decodeCreatedDeck(t, fetchResponse(ctx, http.MethodPost, "/v1/decks?shuffled=true").Body.String())
+	id := created.Guid.String()
+	fetchResponse(ctx, http.MethodGet, "/v1/decks/"+id)
+	fetchResponse(ctx, http.MethodGet, "/v1/decks/"+uuid.NewString())
+	fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?count=3", id))
+	fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/draw/%s?count=2", id))
+	fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/shuffles", id))
+	fetchResponse(ctx, http.MethodPost, "/v1/games/highcard?players=alice,bob")
+	ctx.evict(uuid.New(), "idle")
+	ctx.store = &fileStore{"/dev/null/decks.json"}
+	ctx.Flush()
+
+	w := fetchResponse(ctx, http.MethodGet, "/metrics")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Expected metrics, found %v %v", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+
+	t.Run("counts what happened", func(t *testing.T) {
+		expected := []string{
+			`cards_http_requests_total{handler="Create",method="POST",code="201"} 1`,
+			`cards_http_requests_total{handler="Open",method="GET",code="200"} 1`,
+			`cards_http_requests_total{handler="Open",method="GET",code="404"} 1`,
+			`cards_http_requests_total{handler="Draw",method="POST",code="200"} 2`,
+			`cards_http_request_duration_seconds_bucket{handler="Draw",le="+Inf"} 2`,
+			`cards_http_request_duration_seconds_count{handler="Open"} 2`,
+			`cards_decks 1`,
+			`cards_cards_drawn_total 5`,
+			`cards_shuffles_total 2`,
+			`cards_store_errors_total 1`,
+			`cards_game_sessions{type="highcard"} 1`,
+			// counted per server rather than per process
+			`cards_deck_evictions_total{reason="idle"} 1`,
+		}
+		for _, line := range expected {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("Expected %v in %v", line, body)
+			}
+		}
+	})
+
+	t.Run("is in the text format", func(t *testing.T) {
+		sample := regexp.MustCompile(`^[a-z_]+(\{[a-z]+="[^"]*"(,[a-z]+="[^"]*")*\})? [0-9.e+-]+$`)
+		comment := regexp.MustCompile(`^# (HELP|TYPE) [a-z_]+ .+$`)
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if !sample.MatchString(line) && !comment.MatchString(line) {
+				t.Errorf("Expected a sample or a comment, found %q", line)
+			}
+		}
+	})
+
+	t.Run("keeps buckets cumulative", func(t *testing.T) {
+		bucket := regexp.MustCompile(`cards_http_request_duration_seconds_bucket\{handler="Open",le="[^"]+"\} (\d+)`)
+		previous := 0
+		for _, match := range bucket.FindAllStringSubmatch(body, -1) {
+			count, _ := strconv.Atoi(match[1])
+			if count < previous {
+				t.Errorf("Expected buckets not to shrink, found %v after %v", count, previous)
+			}
+			previous = count
+		}
+		if previous != 2 {
+			t.Errorf("Expected the last bucket to count every request, found %v", previous)
+		}
+	})
+}
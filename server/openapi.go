@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// describes every /v1 route. openapi_test.go keeps it honest by checking it
+// against the routes and what the handlers actually answer
+//
+//go:embed openapi.json
+var openapi []byte
+
+// GET /v1/openapi.json
+func (ctx *HandlerContext) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// only what's needed to check the document against the handlers
+type openapiDoc struct {
+	Paths      map[string]map[string]openapiOperation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*openapiSchema  `json:"schemas"`
+		Responses map[string]openapiResponse `json:"responses"`
+	} `json:"components"`
+}
+
+type openapiOperation struct {
+	OperationId string                     `json:"operationId"`
+	Responses   map[string]openapiResponse `json:"responses"`
+}
+
+type openapiResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *openapiSchema `json:"schema"`
+	} `json:"content"`
+}
+
+type openapiSchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Enum                 []string                  `json:"enum"`
+	Nullable             bool                      `json:"nullable"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openapiSchema `json:"properties"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+	Items                *openapiSchema            `json:"items"`
+	OneOf                []*openapiSchema          `json:"oneOf"`
+}
+
+func loadOpenAPI(t *testing.T) openapiDoc {
+	t.Helper()
+	var doc openapiDoc
+	if err := json.Unmarshal(openapi, &doc); err != nil {
+		t.Fatalf("Failed to parse openapi.json: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	decks := make(map[uuid.UUID]deck.Deck)
+	mux := NewHandlerContext(&decks).Routes()
+
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			method = strings.ToUpper(method)
+			url := strings.NewReplacer("{id}", uuid.NewString(), "{type}", "highcard").Replace(path)
+			_, pattern := mux.Handler(httptest.NewRequest(method, url, nil))
+			if pattern != method+" "+path {
+				msg := "Expected %v (%v) to be routed to %v but found %q instead"
+				t.Errorf(msg, operation.OperationId, url, method+" "+path, pattern)
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	doc := loadOpenAPI(t)
+	types := map[string]any{
+		"CreatedDeck":   CreatedDeck{},
+		"OpenDeck":      OpenDeck{},
+		"OpenPile":      OpenPile{},
+		"OpenCard":      OpenCard{},
+		"OpenView":      OpenView{},
+		"Problem":       Problem{},
+		"Health":        Health{},
+		"Event":         Event{},
+		"Snapshot":      deck.Snapshot{},
+		"SnapshotPile":  deck.SnapshotPile{},
+		"SnapshotEvent": deck.SnapshotEvent{},
+	}
+
+	for name, value := range types {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Fatalf("Expected %v to be documented", name)
+			}
+
+			fields, required := jsonFields(reflect.TypeOf(value))
+			properties := []string{}
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			if !slices.Equal(fields, properties) {
+				t.Errorf("Expected properties %v but found %v instead", fields, properties)
+			}
+
+			documented := append([]string{}, schema.Required...)
+			sort.Strings(documented)
+			if !slices.Equal(required, documented) {
+				t.Errorf("Expected %v to be required but found %v instead", required, documented)
+			}
+		})
+	}
+}
+
+// fields that are always written are required, omitempty ones are not
+func jsonFields(typ reflect.Type) ([]string, []string) {
+	fields := []string{}
+	required := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(fields)
+	sort.Strings(required)
+	return fields, required
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	doc := loadOpenAPI(t)
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	check := func(method string, path string, url string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := sendRequest(ctx, method, url, nil, body)
+		if err := doc.validateResponse(method, path, w); err != nil {
+			t.Errorf("%v %v: %v", method, url, err)
+		}
+		return w
+	}
+
+	w := check(http.MethodPost, "/v1/decks", "/v1/decks?cards=AS,KD,QH,JC,10H&player=alice", "")
+	created := decodeCreatedDeck(t, w.Body.String())
+	id := created.Guid.String()
+	check(http.MethodPost, "/v1/decks", "/v1/decks?cards=A?", "")
+	check(http.MethodGet, "/v1/decks/{id}", "/v1/decks/"+id+"?player=alice", "")
+	check(http.MethodGet, "/v1/decks/{id}", "/v1/decks/"+uuid.NewString(), "")
+	check(http.MethodGet, "/v1/decks/{id}", "/v1/decks/"+id+"?player=bob", "")
+	check(http.MethodPost, "/v1/decks/{id}/draws", "/v1/decks/"+id+"/draws?pile=hand&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/draws", "/v1/decks/"+id+"/draws?count=9&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/draws", "/v1/decks/"+id+"/draws?count=x&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/deals", "/v1/decks/"+id+"/deals?players=alice,bob&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/deals", "/v1/decks/"+id+"/deals?players=alice,bob&count=9&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/deals", "/v1/decks/"+id+"/deals?player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/shuffles", "/v1/decks/"+id+"/shuffles?player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/shares", "/v1/decks/"+id+"/shares?with=bob&player=alice", "")
+	check(http.MethodGet, "/v1/decks/{id}", "/v1/decks/"+id+"?player=bob", "")
+	check(http.MethodGet, "/v1/decks/{id}/history", "/v1/decks/"+id+"/history?player=alice", "")
+	check(http.MethodGet, "/v1/decks/{id}/history", "/v1/decks/"+id+"/history?at=1&player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/undo", "/v1/decks/"+id+"/undo?player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/redo", "/v1/decks/"+id+"/redo?player=alice", "")
+	check(http.MethodPost, "/v1/decks/{id}/redo", "/v1/decks/"+id+"/redo?player=alice", "")
+	snapshot := check(http.MethodGet, "/v1/decks/{id}/export", "/v1/decks/"+id+"/export?player=alice", "").Body.String()
+	check(http.MethodPost, "/v1/imports", "/v1/imports?fresh=true&player=alice", snapshot)
+	check(http.MethodPost, "/v1/imports", "/v1/imports?player=alice", snapshot)
+
+	w = check(http.MethodPost, "/v1/games/{type}", "/v1/games/highcard?players=alice,bob&player=alice", "")
+	var view OpenView
+	json.Unmarshal(w.Body.Bytes(), &view)
+	game := fmt.Sprintf("/v1/games/highcard/%s", view.Guid)
+	check(http.MethodPost, "/v1/games/{type}", "/v1/games/uno?players=alice", "")
+	check(http.MethodGet, "/v1/games/{type}/{id}", game+"?player=bob", "")
+	action, _ := json.Marshal(view.Legal[0])
+	check(http.MethodPost, "/v1/games/{type}/{id}/actions", game+"/actions?player=alice", string(action))
+	check(http.MethodPost, "/v1/games/{type}/{id}/actions", game+"/actions?player=alice", string(action))
+	check(http.MethodGet, "/v1/openapi.json", "/v1/openapi.json", "")
+	check(http.MethodGet, "/healthz", "/healthz", "")
+	check(http.MethodGet, "/readyz", "/readyz", "")
+	ctx.draining.Store(true)
+	check(http.MethodGet, "/readyz", "/readyz", "")
+}
+
+func (doc openapiDoc) validateResponse(method string, path string, w *httptest.ResponseRecorder) error {
+	operation, ok := doc.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("%v %v is not documented", method, path)
+	}
+
+	response, ok := operation.Responses[strconv.Itoa(w.Code)]
+	if !ok {
+		return fmt.Errorf("status %v is not documented", w.Code)
+	}
+	if response.Ref != "" {
+		response = doc.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	}
+
+	contentType := w.Header().Get("Content-Type")
+	media, ok := response.Content[contentType]
+	if !ok {
+		return fmt.Errorf("content type %v is not documented for %v", contentType, w.Code)
+	}
+
+	var body any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		return err
+	}
+	return doc.validate(media.Schema, body, "body")
+}
+
+func (doc openapiDoc) validate(schema *openapiSchema, value any, at string) error {
+	if schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	if len(schema.OneOf) > 0 {
+		for _, option := range schema.OneOf {
+			if doc.validate(option, value, at) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v matches none of its schemas", at)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%v is null", at)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%v is not an object", at)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%v lacks %v", at, name)
+			}
+		}
+		for name, property := range object {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				propertySchema, ok = doc.additionalSchema(schema)
+			}
+			if !ok {
+				return fmt.Errorf("%v has undocumented %v", at, name)
+			}
+			if propertySchema == nil {
+				continue
+			}
+			if err := doc.validate(propertySchema, property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%v is not an array", at)
+		}
+		for i, item := range array {
+			if err := doc.validate(schema.Items, item, fmt.Sprintf("%v[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", at)
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			return fmt.Errorf("%v is not one of %v", at, schema.Enum)
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%v is not a number", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%v is not a boolean", at)
+		}
+	}
+	return nil
+}
+
+// additionalProperties is either false, absent (anything goes) or a schema
+func (doc openapiDoc) additionalSchema(schema *openapiSchema) (*openapiSchema, bool) {
+	raw := string(schema.AdditionalProperties)
+	switch raw {
+	case "false":
+		return nil, false
+	case "", "true", "{}":
+		return nil, true
+	}
+	var additional openapiSchema
+	if err := json.Unmarshal(schema.AdditionalProperties, &additional); err != nil {
+		return nil, false
+	}
+	return &additional, true
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrInvalidGuid      = errors.New("Invalid identifier")
+	ErrInvalidParameter = errors.New("Invalid parameter")
+)
+
+func parseGuid(id string) (uuid.UUID, error) {
+	guid, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, &InputError{"id", id, ErrInvalidGuid}
+	}
+	return guid, nil
+}
+
+// InputError points at the query parameter whose value was refused so
+// clients know which part of their request to fix
+type InputError struct {
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%v: %v=%v", e.Err, e.Param, e.Value)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+func (e *InputError) Is(target error) bool {
+	return target == ErrInvalidParameter
+}
+
+// Problem is an RFC 7807 problem details object. Code is stable and is what
+// clients should switch on, Title and Detail are meant for humans
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
+	Param  string `json:"param,omitempty"`
+	Input  string `json:"input,omitempty"`
+	// only set when there weren't enough cards left to draw
+	Remaining *int `json:"remaining,omitempty"`
+}
+
+// the first match wins so more specific errors go first
+var problemCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrInvalidGuid, "invalid_guid"},
+	{deck.ErrInvalidCard, "invalid_card"},
+	{deck.ErrNotEnoughCards, "not_enough_cards"},
+	{deck.ErrInvalidCount, "invalid_count"},
+	{deck.ErrForeignPile, "foreign_pile"},
+	{ErrDeckNotFound, "deck_not_found"},
+	{ErrGameNotFound, "game_not_found"},
+	{ErrDeckExpired, "deck_expired"},
+	{ErrGameExpired, "game_expired"},
+	{ErrEventsGone, "events_gone"},
+	{ErrIdempotencyKeyReused, "idempotency_key_reused"},
+	{ErrVersionMismatch, "version_mismatch"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrDeckTooLarge, "deck_too_large"},
+	{ErrTooManyDecks, "too_many_decks"},
+	{ErrInvalidParameter, "invalid_parameter"},
+}
+
+func (p Problem) Error() string {
+	return p.Detail
+}
+
+// errors nobody gave a code to get one derived from their status, as in
+// forbidden or too_many_requests
+func intoProblem(status int, err error) Problem {
+	title := http.StatusText(status)
+	code := strings.ToLower(strings.ReplaceAll(title, " ", "_"))
+	for _, known := range problemCodes {
+		if errors.Is(err, known.err) {
+			title = known.err.Error()
+			code = known.code
+			break
+		}
+	}
+
+	problem := Problem{
+		Type:   "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:  title,
+		Status: status,
+		Detail: err.Error(),
+		Code:   code,
+	}
+
+	var inputErr *InputError
+	if errors.As(err, &inputErr) {
+		problem.Param = inputErr.Param
+		problem.Input = inputErr.Value
+	}
+	// narrows a list of cards down to the one that couldn't be parsed
+	var cardErr *deck.InvalidCardError
+	if errors.As(err, &cardErr) {
+		problem.Input = cardErr.Code
+	}
+	var notEnoughErr *deck.NotEnoughCardsError
+	if errors.As(err, &notEnoughErr) {
+		problem.Remaining = &notEnoughErr.Remaining
+	}
+	return problem
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	body, jsonErr := json.Marshal(intoProblem(status, err))
+	if jsonErr != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	created, _ := create(t, ctx, "/create?player=alice")
+	fetch(t, ctx, http.MethodPost, fmt.Sprintf("/draw/%s?pile=hand&player=alice", created.Guid))
+	fetch(t, ctx, http.MethodPost, fmt.Sprintf("/share/%s?with=bob&player=alice", created.Guid))
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		status int
+		code   string
+		param  string
+		input  string
+	}{
+		{
+			"invalid card on create",
+			http.MethodPost, "/v1/decks?cards=AS,A?,KD",
+			http.StatusBadRequest, "invalid_card", "cards", "A?",
+		},
+		{
+			"invalid visibility on create",
+			http.MethodPost, "/v1/decks?visibility=sideways",
+			http.StatusBadRequest, "invalid_parameter", "visibility", "sideways",
+		},
+		{
+			"unknown deck on open",
+			http.MethodGet, fmt.Sprintf("/v1/decks/%s", uuid.New()),
+			http.StatusNotFound, "deck_not_found", "", "",
+		},
+		{
+			"malformed guid on open",
+			http.MethodGet, "/v1/decks/this-is-not-a-guid",
+			http.StatusBadRequest, "invalid_guid", "id", "this-is-not-a-guid",
+		},
+		{
+			"unknown deck on draw",
+			http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws", uuid.New()),
+			http.StatusNotFound, "deck_not_found", "", "",
+		},
+		{
+			"someone else's pile on draw",
+			http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?pile=hand&player=bob", created.Guid),
+			http.StatusForbidden, "foreign_pile", "", "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := fetchResponse(ctx, test.method, test.url)
+			if w.Code != test.status {
+				t.Errorf("Expected %v, found %v", test.status, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("Expected problem+json, found %v", contentType)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode %v: %v", w.Body.String(), err)
+			}
+			if problem.Status != test.status || problem.Code != test.code {
+				msg := "Expected %v %v but found %v %v instead"
+				t.Errorf(msg, test.status, test.code, problem.Status, problem.Code)
+			}
+			if problem.Param != test.param || problem.Input != test.input {
+				msg := "Expected %v=%v to be reported but found %v=%v instead"
+				t.Errorf(msg, test.param, test.input, problem.Param, problem.Input)
+			}
+		})
+	}
+}
+
+func TestProblemCodes(t *testing.T) {
+	t.Run("derives code from status for unknown errors", func(t *testing.T) {
+		problem := intoProblem(http.StatusTooManyRequests, errors.New("slow down"))
+		if problem.Code != "too_many_requests" || problem.Detail != "slow down" {
+			t.Errorf("Expected too_many_requests, found %v", problem)
+		}
+	})
+
+	t.Run("recognizes wrapped errors", func(t *testing.T) {
+		err := fmt.Errorf("%w: %v", ErrDeckExpired, uuid.New())
+		problem := intoProblem(http.StatusGone, err)
+		if problem.Code != "deck_expired" || problem.Type != "/problems/deck-expired" {
+			t.Errorf("Expected deck_expired, found %v", problem)
+		}
+	})
+}
+
+// turns an error response into an error the tests can inspect
+func decodeProblem(body []byte) error {
+	var problem Problem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return err
+	}
+	return problem
+}
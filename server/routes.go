@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Routes maps every endpoint to its handler. The /v1 routes are the
+// current API; the unversioned ones are what clients used before
+// versioning and are kept as aliases so they keep working
+func (ctx *HandlerContext) Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	// logs, metrics and rate limits are named after the handler, as in Create
+	serve := func(pattern string, name string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, ctx.logged(name, ctx.measure(name, ctx.cors(ctx.throttled(name, ctx.authenticated(ctx.limited(name, handler)))))))
+	}
+	handle := func(pattern string, handler http.HandlerFunc) {
+		serve(pattern, handlerName(handler), handler)
+	}
+	// every POST changes something and may be retried with an Idempotency-Key
+	mutate := func(pattern string, handler http.HandlerFunc) {
+		serve(pattern, handlerName(handler), ctx.idempotent(handler))
+	}
+
+	mutate("POST /v1/decks", ctx.Create)
+	handle("GET /v1/decks/{id}", ctx.Open)
+	mutate("POST /v1/decks/{id}/draws", ctx.Draw)
+	mutate("POST /v1/decks/{id}/deals", ctx.Deal)
+	mutate("POST /v1/decks/{id}/shuffles", ctx.Shuffle)
+	mutate("POST /v1/decks/{id}/shares", ctx.Share)
+	handle("GET /v1/decks/{id}/history", ctx.History)
+	mutate("POST /v1/decks/{id}/undo", ctx.Undo)
+	mutate("POST /v1/decks/{id}/redo", ctx.Redo)
+	handle("GET /v1/decks/{id}/export", ctx.Export)
+	handle("GET /v1/decks/{id}/events", ctx.Events)
+	handle("GET /v1/decks/{id}/ws", ctx.WebSocket)
+	mutate("POST /v1/imports", ctx.Import)
+	mutate("POST /v1/games/{type}", ctx.CreateGame)
+	handle("GET /v1/games/{type}/{id}", ctx.ViewGame)
+	mutate("POST /v1/games/{type}/{id}/actions", ctx.ActOnGame)
+	handle("GET /v1/games/{type}/{id}/events", ctx.Events)
+	handle("GET /v1/games/{type}/{id}/ws", ctx.WebSocket)
+	mux.HandleFunc("GET /v1/openapi.json", ctx.cors(ctx.OpenAPI))
+
+	mutate("POST /create", ctx.Create)
+	handle("GET /open/{id}", ctx.Open)
+	mutate("POST /draw/{id}", ctx.Draw)
+	mutate("POST /shuffle/{id}", ctx.Shuffle)
+	mutate("POST /share/{id}", ctx.Share)
+	handle("GET /history/{id}", ctx.History)
+	mutate("POST /undo/{id}", ctx.Undo)
+	mutate("POST /redo/{id}", ctx.Redo)
+	handle("GET /export/{id}", ctx.Export)
+	mutate("POST /import", ctx.Import)
+	mutate("POST /games/{type}", ctx.CreateGame)
+	handle("GET /games/{type}/{id}", ctx.ViewGame)
+	mutate("POST /games/{type}/{id}", ctx.ActOnGame)
+	handle("GET /ws/{id}", ctx.WebSocket)
+	handle("GET /events/{id}", ctx.Events)
+
+	mux.HandleFunc("OPTIONS /", ctx.preflight(mux))
+	mux.HandleFunc("GET /healthz", ctx.Healthz)
+	mux.HandleFunc("GET /readyz", ctx.Readyz)
+	mux.HandleFunc("GET /metrics", ctx.Metrics)
+	return mux
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	w := fetchResponse(ctx, http.MethodPost, "/v1/decks?cards=AS,KD,QH")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 on create, found %v", w.Code)
+	}
+	created := decodeCreatedDeck(t, w.Body.String())
+	location := fmt.Sprintf("/v1/decks/%s", created.Guid)
+	if w.Header().Get("Location") != location {
+		msg := "Expected Location to be %v but found %v instead"
+		t.Errorf(msg, location, w.Header().Get("Location"))
+	}
+
+	t.Run("draws through versioned route", func(t *testing.T) {
+		body := fetch(t, ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?count=2", created.Guid))
+		var cards []OpenCard
+		if err := json.Unmarshal([]byte(body), &cards); err != nil || len(cards) != 2 {
+			t.Fatalf("Expected 2 cards, found %v", body)
+		}
+	})
+
+	t.Run("keeps legacy routes as aliases", func(t *testing.T) {
+		var legacy, versioned OpenDeck
+		json.Unmarshal([]byte(fetch(t, ctx, http.MethodGet, fmt.Sprintf("/open/%s", created.Guid))), &legacy)
+		json.Unmarshal([]byte(fetch(t, ctx, http.MethodGet, location)), &versioned)
+		if legacy.Guid != created.Guid || legacy.RemainingCardCount != versioned.RemainingCardCount {
+			t.Errorf("Expected both routes to open the same deck, found %v and %v", legacy, versioned)
+		}
+	})
+
+	t.Run("answers 404 for unknown deck", func(t *testing.T) {
+		for _, url := range []string{"/v1/decks/%s", "/open/%s", "/v1/decks/%s/history"} {
+			w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf(url, uuid.New()))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 for %v, found %v", url, w.Code)
+			}
+		}
+	})
+
+	t.Run("answers 400 for malformed guid", func(t *testing.T) {
+		w := fetchResponse(ctx, http.MethodGet, "/v1/decks/this-is-not-a-guid")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, found %v", w.Code)
+		}
+	})
+
+	t.Run("rejects wrong method", func(t *testing.T) {
+		w := fetchResponse(ctx, http.MethodGet, fmt.Sprintf("/v1/decks/%s/draws", created.Guid))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected 405, found %v", w.Code)
+		}
+	})
+	t.Run("leaves the process's variables unpublished", func(t *testing.T) {
+		// expvar would answer with the command line and whatever secret is in it
+		w := fetchResponse(ctx, http.MethodGet, "/debug/vars")
+		if w.Code == http.StatusOK || strings.Contains(w.Body.String(), "cmdline") {
+			t.Errorf("Expected no variables, found %v %v", w.Code, w.Body.String())
+		}
+	})
+}
@@ -1,85 +1,257 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"example.com/deck"
+	"example.com/game"
+	"flag"
 	"fmt"
 	"github.com/google/uuid"
-	"io"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"log/slog"
+	"net"
 	"net/http"
-	"regexp"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
-// usage of a router package is the correct alternative to manual regexp parsing
-// but the router didn't play well with the testing library
-var GuidFromUrl = regexp.MustCompile(`/(open|draw)/([\w-]+)`)
+var (
+	ErrDeckNotFound = errors.New("There's no such deck")
+	ErrGameNotFound = errors.New("There's no such game")
+)
 
+// handlers run concurrently so every access to decks and sessions (and
+// everything kept alongside decks) must hold mu
 type HandlerContext struct {
-	decks *map[uuid.UUID]deck.Deck
+	mu         sync.Mutex
+	decks      *map[uuid.UUID]deck.Deck
+	sessions   *map[uuid.UUID]*game.Session
+	leases     map[uuid.UUID]*lease
+	tombstones map[uuid.UUID]time.Time
+	retention  Retention
+	now        func() time.Time
+	auth       *Authenticator
+	broker     *Broker
+	// responses kept for retries carrying an Idempotency-Key
+	idempotency       map[idempotencyScope]*storedResponse
+	idempotencyWindow time.Duration
+	crossOrigin       CORS
+	store             Store
+	// the last flush failed when set
+	flushErr error
+	// set once the server is shutting down
+	draining atomic.Bool
+	metrics  *Metrics
+	logger   *slog.Logger
+	// spans are only exported when set
+	tracer  *Tracer
+	limits  Limits
+	limiter *RateLimiter
 }
 
 func NewHandlerContext(decks *map[uuid.UUID]deck.Deck) *HandlerContext {
 	if decks == nil {
 		panic("decks must de defined!")
 	}
-	return &HandlerContext{decks}
+	sessions := make(map[uuid.UUID]*game.Session)
+	return &HandlerContext{
+		decks:      decks,
+		sessions:   &sessions,
+		leases:     map[uuid.UUID]*lease{},
+		tombstones: map[uuid.UUID]time.Time{},
+		retention:  DefaultRetention(),
+		now:        time.Now,
+		broker:     NewBroker(256, 64),
+
+		idempotency:       map[idempotencyScope]*storedResponse{},
+		idempotencyWindow: 24 * time.Hour,
+		crossOrigin:       DefaultCORS(),
+		store:             memoryStore{},
+		metrics:           NewMetrics(),
+		logger:            slog.New(slog.DiscardHandler),
+		limits:            DefaultLimits(),
+		limiter:           NewRateLimiter(),
+	}
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// returns the exit code: 2 for an invalid configuration and 1 when the
+// server failed or didn't stop cleanly
+func run() int {
+	config, err := LoadConfig(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 2
+	}
+	config.Print(os.Stdout)
+
 	decks := make(map[uuid.UUID]deck.Deck)
 	ctx := NewHandlerContext(&decks)
-	http.HandleFunc("/create", ctx.Create)
-	http.HandleFunc("/open/", ctx.Open)
-	http.HandleFunc("/draw/", ctx.Draw)
+	config.apply(ctx)
+	ctx.logger = NewLogger(os.Stderr, config.LogFormat, config.LogLevel)
+	if config.Trace {
+		ctx.tracer = NewTracer(os.Stdout)
+	}
+	if err := ctx.Load(); err != nil {
+		ctx.logger.Error("Failed to load decks", "error", err)
+		return 1
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	stopJanitor := ctx.StartJanitor(time.Minute)
+	defer stopJanitor()
+	stopFlusher := ctx.StartFlusher(time.Minute)
 
-	err := http.ListenAndServe(":8000", nil)
+	grpcOptions := []grpc.ServerOption{}
+	if config.TLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCert, config.TLSKey)
+		if err != nil {
+			ctx.logger.Error("Failed to load the certificate", "file", config.TLSCert, "error", err)
+			return 1
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(creds))
+	}
+	grpcServer := NewGRPCServer(ctx, grpcOptions...)
+	server := config.httpServer(ctx.Routes())
+
+	failed := make(chan error, 2)
+	listener, err := net.Listen("tcp", config.GRPCAddr)
 	if err != nil {
-		panic(err)
+		failed <- err
+	} else {
+		go func() { failed <- grpcServer.Serve(listener) }()
+	}
+	go func() {
+		if config.TLSCert != "" {
+			failed <- server.ListenAndServeTLS(config.TLSCert, config.TLSKey)
+		} else {
+			failed <- server.ListenAndServe()
+		}
+	}()
+
+	ctx.logger.Info("Serving", "addr", config.Addr, "grpc_addr", config.GRPCAddr, "decks", len(decks))
+	code := 0
+	select {
+	case err := <-failed:
+		ctx.logger.Error("Failed to serve", "error", err)
+		code = 1
+	case <-signals.Done():
+		ctx.logger.Info("Shutting down")
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := ctx.Shutdown(c, server, grpcServer); err != nil {
+		ctx.logger.Error("Requests were cut off", "error", err)
+		code = 1
+	}
+	if err := stopFlusher(); err != nil {
+		ctx.logger.Error("Failed to flush decks", "error", err)
+		code = 1
 	}
+	return code
 }
 
-// POST /create?cards=A2,8C&shuffled where cards and shuffled are optional
+// POST /v1/decks?cards=A2,8C&shuffled&visibility=facedown&rules=highcard&ttl=1h
+// where cards, shuffled, visibility (public, owner, facedown or hidden), rules
+// (the type of game whose rules govern the deck) and ttl (how long the deck
+// may sit idle before being evicted) are optional
 func (ctx *HandlerContext) Create(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
-
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	query := r.URL.Query()
+	options := deckOptions{
+		cards:      query.Get("cards"),
+		shuffled:   strings.ToLower(query.Get("shuffled")) == "true",
+		visibility: query.Get("visibility"),
+		rules:      query.Get("rules"),
+		ttl:        query.Get("ttl"),
+	}
+	deck, status, err := ctx.createDeck(requestingPlayer(r), options)
+	if err != nil {
+		writeError(w, status, err)
 		return
 	}
+	annotate(r, slog.String("deck", deck.Guid.String()))
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/decks/%s", deck.Guid))
+	setETag(w, &deck)
+	respond(w, r, http.StatusCreated, intoCreatedDeck(deck))
+}
+
+// what it takes to create a deck, whichever API was asked to. The zero value
+// is a full deck in order
+type deckOptions struct {
+	cards      string
+	shuffled   bool
+	visibility string
+	rules      string
+	ttl        string
+}
+
+// must be called holding ctx.mu. The status says how to answer when
+// creating the deck failed
+func (ctx *HandlerContext) createDeck(owner string, options deckOptions) (deck.Deck, int, error) {
+	if err := ctx.checkDeckSize(countCodes(options.cards)); err != nil {
+		return deck.NewEmptyDeck(), http.StatusBadRequest, err
+	}
 
-	deck, err := deriveDeck(r)
+	deck, err := deriveDeck(options.cards)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		msg := fmt.Sprintf("%v\n", err)
-		io.WriteString(w, msg)
-		return
+		return deck, http.StatusBadRequest, err
 	}
 
-	shuffled := r.URL.Query().Get("shuffled")
-	shouldShuffle := strings.ToLower(shuffled) == "true"
-	if shouldShuffle {
-		deck.Shuffle()
+	deck.Owner = owner
+	if err := ctx.checkQuota(deck.Owner); err != nil {
+		return deck, http.StatusConflict, err
 	}
 
-	(*ctx.decks)[deck.Guid] = deck
-	createdDeck := intoCreatedDeck(deck)
-	response, err := createdDeck.toJson()
+	ttl, err := ctx.parseTTL(options.ttl)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		msg := fmt.Sprintf("%v\n", err)
-		io.WriteString(w, msg)
-		return
+		return deck, http.StatusBadRequest, err
+	}
+	if options.rules != "" {
+		if _, err := game.UndoPolicyFor(options.rules); err != nil {
+			return deck, http.StatusBadRequest, &InputError{"rules", options.rules, err}
+		}
+		deck.Rules = options.rules
+	}
+	if options.visibility != "" {
+		deck.Visibility, err = parseVisibility(options.visibility)
+		if err != nil {
+			return deck, http.StatusBadRequest, &InputError{"visibility", options.visibility, err}
+		}
+	}
+
+	if options.shuffled {
+		deck.Shuffle()
+		ctx.metrics.shuffled()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	io.WriteString(w, response)
+	e := deck.RecordCreate(deck.Owner)
+	(*ctx.decks)[deck.Guid] = deck
+	ctx.lease(deck.Guid, ttl)
+	ctx.broker.Publish(fromDeckEvent(&deck, e))
+	return deck, http.StatusCreated, nil
 }
 
-func deriveDeck(r *http.Request) (deck.Deck, error) {
-	codes := r.URL.Query().Get("cards")
+func deriveDeck(codes string) (deck.Deck, error) {
 	if codes == "" {
 		deck := deck.NewDefaultDeck()
 		return deck, nil
@@ -87,7 +259,7 @@ func deriveDeck(r *http.Request) (deck.Deck, error) {
 
 	cards, err := parseCards(codes)
 	if err != nil {
-		return deck.NewEmptyDeck(), err
+		return deck.NewEmptyDeck(), &InputError{"cards", codes, err}
 	}
 	return deck.NewDeck(cards), nil
 }
@@ -105,107 +277,306 @@ func parseCards(query string) ([]deck.Card, error) {
 	return cards, nil
 }
 
-// GET /open/{guid}
+// GET /v1/decks/{guid}
 func (ctx *HandlerContext) Open(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	player := requestingPlayer(r)
+	deck, status, err := ctx.openDeck(r.PathValue("id"), player)
+	if err != nil {
+		writeError(w, status, err)
 		return
 	}
 
-	deck, err := retrieveDeck(ctx, r)
+	setETag(w, &deck)
+	respond(w, r, http.StatusOK, intoOpenDeck(deck, player))
+}
+
+// must be called holding ctx.mu
+func (ctx *HandlerContext) openDeck(id string, player string) (deck.Deck, int, error) {
+	deck, err := ctx.findDeck(id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		io.WriteString(w, fmt.Sprintf("%v", err))
-		return
+		return deck, retrieveStatus(err), err
 	}
 
-	openDeck := intoOpenDeck(deck)
-	json, jsonErr := openDeck.toJson()
-	if jsonErr != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		io.WriteString(w, fmt.Sprintf("%v", jsonErr))
+	if err := authorizePlayer(player, &deck); err != nil {
+		return deck, http.StatusForbidden, err
+	}
+	return deck, http.StatusOK, nil
+}
+
+// POST /v1/decks/{guid}/draws?count=2&pile=hand&allow_partial=true where
+// count is optional and pile, when given, keeps the drawn cards on the table
+// in a pile owned by the player. The pile is only visible to its owner unless
+// pile_visibility says otherwise. Asking for more cards than are left is an
+// error unless allow_partial settles for whatever is left
+func (ctx *HandlerContext) Draw(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	query := r.URL.Query()
+	options := drawOptions{
+		pile:           query.Get("pile"),
+		pileVisibility: query.Get("pile_visibility"),
+		allowPartial:   strings.ToLower(query.Get("allow_partial")) == "true",
+		ifMatch:        r.Header.Get("If-Match"),
+	}
+	deck, cards, status, err := ctx.drawFrom(r.PathValue("id"), requestingPlayer(r), query.Get("count"), options)
+	if err != nil {
+		writeError(w, status, err)
 		return
 	}
+	// how many, never which
+	annotate(r, slog.Int("drawn", len(cards)))
 
-	w.Header().Set("Content-Type", "application/json")
-	io.WriteString(w, json)
+	setETag(w, &deck)
+	respond(w, r, http.StatusOK, OpenCards(IntoOpenCards(cards)))
 }
 
-// GET /draw/{guid}?count=2 where count is optional
-func (ctx *HandlerContext) Draw(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
+// ifMatch is a list of ETags as in If-Match
+type drawOptions struct {
+	pile           string
+	pileVisibility string
+	allowPartial   bool
+	ifMatch        string
+}
 
-	// Getting CORS error with http.MethodPatch
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// must be called holding ctx.mu. count is left as given so that it's only
+// looked at once the deck is known to exist and be accessible
+func (ctx *HandlerContext) drawFrom(id string, player string, count string, options drawOptions) (deck.Deck, []deck.Card, int, error) {
+	d, status, err := ctx.openDeck(id, player)
+	if err != nil {
+		return d, []deck.Card{}, status, err
 	}
 
-	deck, err := retrieveDeck(ctx, r)
+	if err := matchVersion(options.ifMatch, &d); err != nil {
+		return d, []deck.Card{}, http.StatusPreconditionFailed, err
+	}
+
+	n, err := parseCount(count)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		io.WriteString(w, fmt.Sprintf("%v", err))
-		return
+		return d, []deck.Card{}, http.StatusBadRequest, err
+	}
+	if options.allowPartial && n > d.RemainingCardCount() {
+		n = d.RemainingCardCount()
+	}
+
+	visibility, err := pileVisibility(options.pileVisibility)
+	if err != nil {
+		return d, []deck.Card{}, http.StatusBadRequest, err
+	}
+	cards, err := d.DrawFor(player, options.pile, visibility, n)
+	if err != nil {
+		return d, []deck.Card{}, drawStatus(err), err
+	}
+
+	e := d.RecordDraw(player, options.pile, cards)
+	(*ctx.decks)[d.Guid] = d
+	ctx.metrics.drew(len(cards))
+	ctx.broker.Publish(fromDeckEvent(&d, e))
+	return d, cards, http.StatusOK, nil
+}
+
+// only the owner sees piles by default
+func pileVisibility(param string) (deck.Visibility, error) {
+	if param == "" {
+		return deck.OwnerOnly, nil
+	}
+	visibility, err := parseVisibility(param)
+	if err != nil {
+		return visibility, &InputError{"pile_visibility", param, err}
+	}
+	return visibility, nil
+}
+
+func parseCount(param string) (int, error) {
+	if param == "" {
+		return 1, nil
 	}
 
-	param := r.URL.Query().Get("count")
 	count, err := strconv.Atoi(param)
+	if err != nil || count < 1 {
+		return 0, &InputError{"count", param, deck.ErrInvalidCount}
+	}
+	return count, nil
+}
+
+func drawStatus(err error) int {
+	switch {
+	case errors.Is(err, deck.ErrForeignPile):
+		return http.StatusForbidden
+	case errors.Is(err, deck.ErrNotEnoughCards):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func parseVisibility(param string) (deck.Visibility, error) {
+	return deck.ParseVisibility(strings.ToLower(param))
+}
+
+// POST /v1/decks/{guid}/deals?players=alice,bob&count=5 deals 5 cards to alice and
+// bob in turn, into piles named after and owned by each of them. The piles
+// are only visible to their owners unless pile_visibility says otherwise, so
+// the dealer gets to count the cards but not to see them. Either everyone
+// gets their cards or nothing is dealt
+func (ctx *HandlerContext) Deal(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	player := requestingPlayer(r)
+	d, status, err := ctx.openDeck(r.PathValue("id"), player)
 	if err != nil {
-		count = 1
+		writeError(w, status, err)
+		return
 	}
 
-	cards := deck.Draw(count)
-	(*ctx.decks)[deck.Guid] = deck
-	body, err := marshallOpenCardsToJson(IntoOpenCards(cards))
+	if err := checkVersion(r, &d); err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	query := r.URL.Query()
+	players := []string{}
+	for _, name := range strings.Split(query.Get("players"), ",") {
+		if name != "" {
+			players = append(players, name)
+		}
+	}
+	if len(players) == 0 {
+		writeError(w, http.StatusBadRequest, &InputError{"players", query.Get("players"), ErrInvalidParameter})
+		return
+	}
 
+	count, err := parseCount(query.Get("count"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		io.WriteString(w, fmt.Sprintf("%v", err))
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	visibility, err := pileVisibility(query.Get("pile_visibility"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := d.Deal(player, players, count, visibility)
+	if err != nil {
+		writeError(w, drawStatus(err), err)
+		return
+	}
 
-	io.WriteString(w, body)
+	(*ctx.decks)[d.Guid] = d
+	ctx.metrics.drew(len(events))
+	for i, e := range events {
+		// every event deals a single card, and those after it were still
+		// in the deck
+		event := fromDeckEvent(&d, e)
+		event.Remaining += len(events) - 1 - i
+		ctx.broker.Publish(event)
+	}
+	annotate(r, slog.Int("drawn", len(events)))
 
+	setETag(w, &d)
+	respond(w, r, http.StatusOK, intoOpenDeck(d, player))
 }
 
-func retrieveDeck(ctx *HandlerContext, r *http.Request) (deck.Deck, error) {
-	guid, err := extractGuidFromUrlPath(r.URL.Path)
+// POST /v1/decks/{guid}/shuffles shuffles whatever is left in the deck
+func (ctx *HandlerContext) Shuffle(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	deck, err := retrieveDeck(ctx, r)
 	if err != nil {
-		msg := fmt.Sprintf("%v", err)
-		return deck.NewEmptyDeck(), errors.New(msg)
+		writeError(w, retrieveStatus(err), err)
+		return
 	}
 
-	foundDeck, ok := (*ctx.decks)[guid]
-	if !ok {
-		msg := fmt.Sprintf("There's no deck with identifier %v", guid)
-		return deck.NewEmptyDeck(), errors.New(msg)
+	if err := authorize(r, &deck); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
 	}
 
-	return foundDeck, nil
+	if err := checkVersion(r, &deck); err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	deck.Shuffle()
+	ctx.metrics.shuffled()
+	e := deck.RecordShuffle(requestingPlayer(r))
+	(*ctx.decks)[deck.Guid] = deck
+	ctx.broker.Publish(fromDeckEvent(&deck, e))
+
+	setETag(w, &deck)
+	respond(w, r, http.StatusOK, intoCreatedDeck(deck))
 }
 
-// we could have used r.PathValue("deck_guid") on go 1.22
-func extractGuidFromUrlPath(path string) (uuid.UUID, error) {
-	matches := GuidFromUrl.FindStringSubmatch(path)
+// POST /v1/decks/{guid}/shares?with=bob,carol lets other players use the
+// deck. Only the owner may share it
+func (ctx *HandlerContext) Share(w http.ResponseWriter, r *http.Request) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	deck, err := retrieveDeck(ctx, r)
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	player := requestingPlayer(r)
+	if deck.Owner == "" {
+		msg := fmt.Sprintf("Deck %v has no owner and is already shared with everyone", deck.Guid)
+		writeError(w, http.StatusForbidden, errors.New(msg))
+		return
+	}
 
-	if len(matches) < 3 {
-		msg := fmt.Sprintf("Failed to extract guid from url path %v", path)
-		return uuid.New(), errors.New(msg)
+	if err := authorizeOwner(r, deck.Owner); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
 	}
 
-	guid, err := uuid.Parse(matches[2])
-	if err != nil {
-		return uuid.New(), err
+	if err := checkVersion(r, &deck); err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
 	}
 
-	return guid, nil
+	players := []string{}
+	for _, shared := range strings.Split(r.URL.Query().Get("with"), ",") {
+		if shared != "" {
+			players = append(players, shared)
+		}
+	}
+	deck.Share(players...)
+	e := deck.RecordShare(player, players)
+	(*ctx.decks)[deck.Guid] = deck
+	ctx.broker.Publish(fromDeckEvent(&deck, e))
+
+	setETag(w, &deck)
+	respond(w, r, http.StatusOK, intoOpenDeck(deck, player))
 }
 
-// used to same-origin React app can interact with the server
-func enableCors(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
+func retrieveDeck(ctx *HandlerContext, r *http.Request) (deck.Deck, error) {
+	return ctx.findDeck(r.PathValue("id"))
+}
+
+// must be called holding ctx.mu
+func (ctx *HandlerContext) findDeck(id string) (deck.Deck, error) {
+	guid, err := parseGuid(id)
+	if err != nil {
+		return deck.NewEmptyDeck(), err
+	}
+
+	if ctx.expired(guid) {
+		return deck.NewEmptyDeck(), fmt.Errorf("%w: %v", ErrDeckExpired, guid)
+	}
+
+	foundDeck, ok := (*ctx.decks)[guid]
+	if !ok {
+		return deck.NewEmptyDeck(), fmt.Errorf("%w: %v", ErrDeckNotFound, guid)
+	}
+
+	ctx.touch(guid)
+	return foundDeck, nil
 }
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateDeck(t *testing.T) {
@@ -54,10 +56,10 @@ func TestCreateDeck(t *testing.T) {
 }
 
 func create(t *testing.T, ctx *HandlerContext, url string) (CreatedDeck, error) {
-	r := httptest.NewRequest(http.MethodGet, url, nil)
+	r := httptest.NewRequest(http.MethodPost, url, nil)
 	w := httptest.NewRecorder()
 
-	ctx.Create(w, r)
+	ctx.Routes().ServeHTTP(w, r)
 
 	res := w.Result()
 	defer res.Body.Close()
@@ -66,6 +68,9 @@ func create(t *testing.T, ctx *HandlerContext, url string) (CreatedDeck, error)
 	if err != nil {
 		return CreatedDeck{}, err
 	}
+	if res.StatusCode >= 400 {
+		return CreatedDeck{}, decodeProblem(jsonBytes)
+	}
 
 	var deck CreatedDeck
 	if err := json.Unmarshal(jsonBytes, &deck); err != nil {
@@ -113,11 +118,145 @@ func TestOpenDeck(t *testing.T) {
 	})
 }
 
+func TestDeckVisibility(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	t.Run("face down deck never leaks its cards", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?shuffled=true&visibility=facedown&player=alice")
+		hidden := decks[created.Guid].Cards
+
+		for _, player := range []string{"alice", "bob", ""} {
+			url := fmt.Sprintf("/open/%s?player=%s", created.Guid, player)
+			body := fetch(t, ctx, http.MethodGet, url)
+			assertNoLeaks(t, body, hidden)
+		}
+	})
+
+	t.Run("owner only deck shows cards to its owner", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?visibility=owner&player=alice")
+
+		url := fmt.Sprintf("/open/%s?player=alice", created.Guid)
+		openDeck, _ := open(t, ctx, url)
+		if len(openDeck.Cards) != 52 {
+			t.Errorf("Expected alice to see her 52 cards, found %v", len(openDeck.Cards))
+		}
+
+		url = fmt.Sprintf("/open/%s?player=bob", created.Guid)
+		body := fetch(t, ctx, http.MethodGet, url)
+		assertNoLeaks(t, body, decks[created.Guid].Cards)
+	})
+
+	t.Run("hand drawn into is only visible to its owner", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?shuffled=true&visibility=facedown")
+		url := fmt.Sprintf("/draw/%s?count=5&pile=alice&player=alice", created.Guid)
+		drawn, _ := draw(t, ctx, url)
+		if len(drawn) != 5 {
+			t.Errorf("Expected alice to have been shown the 5 cards she drew")
+		}
+
+		url = fmt.Sprintf("/draw/%s?count=5&pile=bob&player=bob", created.Guid)
+		draw(t, ctx, url)
+
+		d := decks[created.Guid]
+		hidden := append([]deck.Card{}, d.Cards...)
+		hidden = append(hidden, d.Piles["alice"].Cards...)
+
+		url = fmt.Sprintf("/open/%s?player=bob", created.Guid)
+		body := fetch(t, ctx, http.MethodGet, url)
+		assertNoLeaks(t, body, hidden)
+
+		url = fmt.Sprintf("/open/%s?player=alice", created.Guid)
+		openDeck, _ := open(t, ctx, url)
+		if len(openDeck.Piles["alice"].Cards) != 5 {
+			t.Errorf("Expected alice to see her hand, found %v", openDeck.Piles["alice"])
+		}
+	})
+
+	t.Run("fails to draw into someone else's pile", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		url := fmt.Sprintf("/draw/%s?pile=hand&player=alice", created.Guid)
+		draw(t, ctx, url)
+
+		url = fmt.Sprintf("/draw/%s?pile=hand&player=bob", created.Guid)
+		_, err := draw(t, ctx, url)
+		if err == nil {
+			t.Errorf("Expected err since bob cannot draw into alice's hand")
+		}
+	})
+}
+
+func fetch(t *testing.T, ctx *HandlerContext, method string, url string) string {
+	return fetchResponse(ctx, method, url).Body.String()
+}
+
+func fetchResponse(ctx *HandlerContext, method string, url string) *httptest.ResponseRecorder {
+	return sendRequest(ctx, method, url, nil, "")
+}
+
+// as fetchResponse, with headers such as If-Match and a body
+func sendRequest(ctx *HandlerContext, method string, url string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	ctx.Routes().ServeHTTP(w, r)
+	return w
+}
+
+// starts a request whose body is only sent once the returned func is called,
+// which then waits for the response, as a slow client would
+func slowRequest(ctx *HandlerContext, method string, url string, body string) func() *httptest.ResponseRecorder {
+	reader, writer := io.Pipe()
+	r := httptest.NewRequest(method, url, reader)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx.Routes().ServeHTTP(w, r)
+	}()
+
+	return func() *httptest.ResponseRecorder {
+		io.WriteString(writer, body)
+		writer.Close()
+		<-done
+		return w
+	}
+}
+
+// fails unless request answers while something else may be holding on
+func assertNotBlocked(t *testing.T, request func()) {
+	t.Helper()
+	answered := make(chan struct{})
+	go func() {
+		defer close(answered)
+		request()
+	}()
+	select {
+	case <-answered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected an answer while a slow client is sending its body")
+	}
+}
+
+// card codes are always quoted in our JSON so searching for the quoted code
+// avoids false positives like "AD" in "SPADES"
+func assertNoLeaks(t *testing.T, body string, hidden []deck.Card) {
+	t.Helper()
+	for _, card := range hidden {
+		code := fmt.Sprintf("%q", card.Code())
+		if strings.Contains(body, code) {
+			t.Errorf("Expected %v not to be leaked in %v", code, body)
+		}
+	}
+}
+
 func open(t *testing.T, ctx *HandlerContext, url string) (OpenDeck, error) {
 	r := httptest.NewRequest(http.MethodGet, url, nil)
 	w := httptest.NewRecorder()
 
-	ctx.Open(w, r)
+	ctx.Routes().ServeHTTP(w, r)
 
 	res := w.Result()
 	defer res.Body.Close()
@@ -126,6 +265,9 @@ func open(t *testing.T, ctx *HandlerContext, url string) (OpenDeck, error) {
 	if err != nil {
 		return OpenDeck{}, err
 	}
+	if res.StatusCode >= 400 {
+		return OpenDeck{}, decodeProblem(jsonBytes)
+	}
 
 	var deck OpenDeck
 	if err := json.Unmarshal(jsonBytes, &deck); err != nil {
@@ -173,13 +315,99 @@ func TestDrawCards(t *testing.T) {
 			t.Errorf(msg, n)
 		}
 	})
+
+	t.Run("rejects malformed counts", func(t *testing.T) {
+		deck, _ := create(t, ctx, "/create")
+		for _, count := range []string{"two", "-1", "0", "1.5"} {
+			url := fmt.Sprintf("/draw/%s?count=%s", deck.Guid, count)
+			_, err := draw(t, ctx, url)
+			problem, ok := err.(Problem)
+			if !ok || problem.Status != http.StatusBadRequest || problem.Code != "invalid_count" {
+				t.Errorf("Expected count=%v to be rejected, found %v", count, err)
+			}
+		}
+
+		openDeck, _ := open(t, ctx, fmt.Sprintf("/open/%s", deck.Guid))
+		if openDeck.RemainingCardCount != 52 {
+			t.Errorf("Expected rejected draws to leave the deck untouched")
+		}
+	})
+
+	t.Run("fails to draw more cards than remain", func(t *testing.T) {
+		deck, _ := create(t, ctx, "/create?cards=AS,KD,QH")
+		_, err := draw(t, ctx, fmt.Sprintf("/draw/%s?count=5", deck.Guid))
+		problem, ok := err.(Problem)
+		if !ok || problem.Status != http.StatusConflict || problem.Code != "not_enough_cards" {
+			t.Fatalf("Expected not_enough_cards, found %v", err)
+		}
+		if problem.Remaining == nil || *problem.Remaining != 3 {
+			t.Errorf("Expected 3 remaining cards to be reported, found %v", problem.Remaining)
+		}
+	})
+
+	t.Run("draws whatever is left when partial draws are allowed", func(t *testing.T) {
+		deck, _ := create(t, ctx, "/create?cards=AS,KD,QH")
+		cards, err := draw(t, ctx, fmt.Sprintf("/draw/%s?count=5&allow_partial=true", deck.Guid))
+		if err != nil || len(cards) != 3 {
+			t.Errorf("Expected the 3 remaining cards to be drawn, found %v (%v)", cards, err)
+		}
+
+		cards, err = draw(t, ctx, fmt.Sprintf("/draw/%s?allow_partial=true", deck.Guid))
+		if err != nil || len(cards) != 0 {
+			t.Errorf("Expected nothing to be drawn from an empty deck, found %v (%v)", cards, err)
+		}
+	})
+}
+
+func TestDeal(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	t.Run("deals into piles only their owners see", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?cards=AS,KD,QH,JC,10H&player=dealer")
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/shares?with=alice,bob&player=dealer", created.Guid))
+		w := fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/deals?players=alice,bob&count=2&player=dealer", created.Guid))
+
+		var dealt OpenDeck
+		json.Unmarshal(w.Body.Bytes(), &dealt)
+		alice := dealt.Piles["alice"]
+		if w.Code != http.StatusOK || alice.Owner != "alice" || alice.Cards != nil || alice.Count == nil || *alice.Count != 2 {
+			t.Fatalf("Expected the dealer to only count alice's cards, found %v %v", w.Code, w.Body)
+		}
+
+		hand, _ := open(t, ctx, fmt.Sprintf("/v1/decks/%s?player=alice", created.Guid))
+		if codes := hand.Piles["alice"].Cards; len(codes) != 2 || codes[0].Code != "10H" || codes[1].Code != "QH" {
+			t.Errorf("Expected alice to see the cards dealt to her in turn, found %v", codes)
+		}
+		assertNoLeaks(t, w.Body.String(), []deck.Card{decks[created.Guid].Piles["bob"].Cards[0]})
+	})
+
+	t.Run("deals nothing unless everyone can be served", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?cards=AS,KD,QH")
+		w := fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/deals?players=alice,bob&count=2", created.Guid))
+		if w.Code != http.StatusConflict || len(decks[created.Guid].Cards) != 3 {
+			t.Errorf("Expected 409 without dealing anything, found %v with %d cards left", w.Code, len(decks[created.Guid].Cards))
+		}
+
+		// twice this count overflows an int
+		w = fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/deals?players=a,b&count=4611686018427387904", created.Guid))
+		if stored := decks[created.Guid]; w.Code != http.StatusConflict || len(stored.Cards) != 3 || len(stored.Piles) != 0 {
+			t.Errorf("Expected 409 without dealing anything, found %v with %d cards and %v", w.Code, len(stored.Cards), stored.Piles)
+		}
+
+		draw(t, ctx, fmt.Sprintf("/draw/%s?pile=bob&player=mallory", created.Guid))
+		w = fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/deals?players=alice,bob", created.Guid))
+		if w.Code != http.StatusForbidden || len(decks[created.Guid].Piles) != 1 {
+			t.Errorf("Expected 403 dealing into mallory's pile named bob, found %v", w.Code)
+		}
+	})
 }
 
 func draw(t *testing.T, ctx *HandlerContext, url string) ([]OpenCard, error) {
-	r := httptest.NewRequest(http.MethodGet, url, nil)
+	r := httptest.NewRequest(http.MethodPost, url, nil)
 	w := httptest.NewRecorder()
 
-	ctx.Draw(w, r)
+	ctx.Routes().ServeHTTP(w, r)
 
 	res := w.Result()
 	defer res.Body.Close()
@@ -188,6 +416,9 @@ func draw(t *testing.T, ctx *HandlerContext, url string) ([]OpenCard, error) {
 	if err != nil {
 		return []OpenCard{}, err
 	}
+	if res.StatusCode >= 400 {
+		return []OpenCard{}, decodeProblem(jsonBytes)
+	}
 
 	var cards []OpenCard
 	if err := json.Unmarshal(jsonBytes, &cards); err != nil {
@@ -203,13 +434,3 @@ func TestContentsOfUrlPath(t *testing.T) {
 		t.Errorf("Expected /create/guid/ but found %v", output.Path)
 	}
 }
-
-func TestExtractGuidFromUrlPath(t *testing.T) {
-	path := "http://127.0.0.1:8000/draw/67e55044-10b1-426f-9247-bb680e5fe0c8/?count=10"
-	actualGuid, _ := extractGuidFromUrlPath(path)
-	expectedGuid, _ := uuid.Parse("67e55044-10b1-426f-9247-bb680e5fe0c8")
-	if actualGuid != expectedGuid {
-		msg := "Expected needle to equal %v but found %v instead"
-		t.Errorf(msg, expectedGuid, actualGuid)
-	}
-}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"net/http"
+	"time"
+)
+
+// the HTTP server with timeouts that keep slow clients (slowloris) from
+// holding on to connections. Streams of events lift the write timeout
+// themselves
+func (c *Config) httpServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: min(c.ReadTimeout, 5*time.Second),
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+}
+
+// stops taking requests and waits for those in flight until c is done, at
+// which point whatever is left is cut off. Streams of events are closed
+// first since they would never finish on their own; clients resume them
+// elsewhere. Decks are left for the caller to flush once nothing can
+// change them anymore
+func (ctx *HandlerContext) Shutdown(c context.Context, server *http.Server, grpcServer *grpc.Server) error {
+	ctx.draining.Store(true)
+	ctx.broker.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	err := server.Shutdown(c)
+	select {
+	case <-stopped:
+	case <-c.Done():
+		grpcServer.Stop()
+		<-stopped
+		if err == nil {
+			err = c.Err()
+		}
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example.com/deck"
+	"example.com/server/cardspb"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	// serves the API next to a request that only finishes once told to
+	start := func(t *testing.T) (*HandlerContext, *http.Server, string, chan struct{}) {
+		decks := make(map[uuid.UUID]deck.Deck)
+		ctx := NewHandlerContext(&decks)
+		release := make(chan struct{})
+		mux := http.NewServeMux()
+		mux.Handle("/", ctx.Routes())
+		mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			io.WriteString(w, "done")
+		})
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		config := DefaultConfig()
+		server := config.httpServer(mux)
+		go server.Serve(listener)
+		t.Cleanup(func() { server.Close() })
+		return ctx, server, "http://" + listener.Addr().String(), release
+	}
+
+	t.Run("drains requests in flight and closes streams", func(t *testing.T) {
+		ctx, server, url, release := start(t)
+		watcher := dialDeckService(t, ctx)
+		created, _ := watcher.CreateDeck(context.Background(), &cardspb.CreateDeckRequest{})
+
+		events, err := http.Get(fmt.Sprintf("%s/v1/decks/%s/events", url, created.DeckId))
+		if err != nil || events.StatusCode != http.StatusOK {
+			t.Fatalf("Expected to stream events, found %v (%v)", events, err)
+		}
+		watch, _ := watcher.WatchDeck(context.Background(), &cardspb.WatchDeckRequest{DeckId: created.DeckId, Since: 1})
+
+		slow := make(chan string)
+		go func() {
+			res, err := http.Get(url + "/slow")
+			if err != nil {
+				slow <- err.Error()
+				return
+			}
+			body, _ := io.ReadAll(res.Body)
+			slow <- string(body)
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		stopped := make(chan error)
+		go func() {
+			c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			stopped <- ctx.Shutdown(c, server, NewGRPCServer(ctx))
+		}()
+
+		if _, err := io.ReadAll(events.Body); err != nil {
+			t.Errorf("Expected the event stream to end, found %v", err)
+		}
+		if _, err := watch.Recv(); err == nil {
+			t.Errorf("Expected the gRPC stream to end")
+		}
+
+		w := httptest.NewRecorder()
+		ctx.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected to no longer be ready, found %v", w.Code)
+		}
+
+		close(release)
+		if body := <-slow; body != "done" {
+			t.Errorf("Expected the slow request to finish, found %v", body)
+		}
+		if err := <-stopped; err != nil {
+			t.Errorf("Expected a clean shutdown, found %v", err)
+		}
+	})
+
+	t.Run("cuts off requests that take too long", func(t *testing.T) {
+		ctx, server, url, release := start(t)
+		defer close(release)
+		go http.Get(url + "/slow")
+		time.Sleep(50 * time.Millisecond)
+
+		c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := ctx.Shutdown(c, server, NewGRPCServer(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the slow request to be cut off, found %v", err)
+		}
+	})
+}
+
+func TestHealth(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	t.Run("is healthy and ready", func(t *testing.T) {
+		for _, path := range []string{"/healthz", "/readyz"} {
+			if w := fetchResponse(ctx, http.MethodGet, path); w.Code != http.StatusOK {
+				t.Errorf("Expected 200 on %v, found %v", path, w.Code)
+			}
+		}
+	})
+
+	t.Run("is not ready when decks cannot be saved", func(t *testing.T) {
+		ctx.store = &fileStore{"/dev/null/decks.json"}
+		w := fetchResponse(ctx, http.MethodGet, "/readyz")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503, found %v: %v", w.Code, w.Body)
+		}
+		if w := fetchResponse(ctx, http.MethodGet, "/healthz"); w.Code != http.StatusOK {
+			t.Errorf("Expected to stay healthy, found %v", w.Code)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// comments sent every so often so that proxies don't time out idle streams
+var heartbeatInterval = 15 * time.Second
+
+// GET /v1/decks/{guid}/events streams the same events as the WebSocket as
+// Server-Sent Events for clients that can't do WebSockets. EventSource sends the id of
+// the last event it saw in Last-Event-ID when reconnecting which is all it
+// takes to resume
+func (ctx *HandlerContext) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+		return
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		// for EventSource polyfills that can't set headers
+		lastEventId = r.URL.Query().Get("last_event_id")
+	}
+
+	since := 0
+	if lastEventId != "" {
+		var err error
+		since, err = strconv.Atoi(lastEventId)
+		if err != nil || since < 0 {
+			err := &InputError{"Last-Event-ID", lastEventId, ErrInvalidParameter}
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	sub, ok := ctx.subscribe(w, r, since)
+	if !ok {
+		return
+	}
+	defer ctx.broker.Unsubscribe(sub)
+
+	// the server's write timeout is meant for single responses. Every write
+	// gets its own deadline instead, as on WebSockets
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+			// either too slow or the server is stopping. Both ways the client
+			// reconnects with Last-Event-ID
+			if !ok {
+				return
+			}
+			if err := writeServerSentEvent(w, e.For(sub.Player)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerSentEvents(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	server := httptest.NewServer(ctx.Routes())
+	defer server.Close()
+
+	created, _ := create(t, ctx, "/create")
+	draw(t, ctx, fmt.Sprintf("/draw/%s?count=2", created.Guid))
+	url := fmt.Sprintf("%s/events/%s", server.URL, created.Guid)
+
+	t.Run("streams events", func(t *testing.T) {
+		stream := subscribeToEvents(t, url, "")
+		defer stream.Close()
+
+		if id, event := nextEvent(t, stream); id != "1" || event != "created" {
+			t.Errorf("Expected first event to be the creation, found %v %v", id, event)
+		}
+
+		if id, event := nextEvent(t, stream); id != "2" || event != "drawn" {
+			t.Errorf("Expected second event to be the draw, found %v %v", id, event)
+		}
+	})
+
+	t.Run("resumes from Last-Event-ID", func(t *testing.T) {
+		stream := subscribeToEvents(t, url, "1")
+		defer stream.Close()
+
+		if id, event := nextEvent(t, stream); id != "2" || event != "drawn" {
+			t.Errorf("Expected to resume with the draw, found %v %v", id, event)
+		}
+	})
+
+	t.Run("sends heartbeats", func(t *testing.T) {
+		heartbeatInterval = 10 * time.Millisecond
+		defer func() { heartbeatInterval = 15 * time.Second }()
+
+		stream := subscribeToEvents(t, url, "2")
+		defer stream.Close()
+
+		line := nextLine(t, stream)
+		if line != ": heartbeat" {
+			t.Errorf("Expected a heartbeat, found %v", line)
+		}
+	})
+
+	t.Run("ends streams when the broker closes", func(t *testing.T) {
+		stream := subscribeToEvents(t, url, "2")
+		defer stream.Close()
+
+		ctx.broker.Close()
+		// blocks until the stream ends
+		rest, err := io.ReadAll(stream.lines)
+		if err != nil || strings.TrimSpace(string(rest)) != "" {
+			t.Errorf("Expected stream to have ended, found %q (%v)", rest, err)
+		}
+	})
+}
+
+type eventStream struct {
+	res   *http.Response
+	lines *bufio.Reader
+}
+
+func (s *eventStream) Close() {
+	s.res.Body.Close()
+}
+
+func subscribeToEvents(t *testing.T, url string, lastEventId string) *eventStream {
+	r, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventId != "" {
+		r.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	res, err := http.DefaultClient.Do(r)
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to subscribe to %v: %v", url, err)
+	}
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, found %v", res.Header.Get("Content-Type"))
+	}
+
+	stream := &eventStream{res, bufio.NewReader(res.Body)}
+	if line := nextLine(t, stream); line != "retry: 3000" {
+		t.Errorf("Expected stream to start with retry, found %v", line)
+	}
+	return stream
+}
+
+// skips blank lines separating events
+func nextLine(t *testing.T, stream *eventStream) string {
+	for {
+		line, err := stream.lines.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read from stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line != "" {
+			return line
+		}
+	}
+}
+
+func nextEvent(t *testing.T, stream *eventStream) (string, string) {
+	id := strings.TrimPrefix(nextLine(t, stream), "id: ")
+	event := strings.TrimPrefix(nextLine(t, stream), "event: ")
+	if data := nextLine(t, stream); !strings.HasPrefix(data, "data: {") {
+		t.Errorf("Expected event data to be JSON, found %v", data)
+	}
+	return id, event
+}
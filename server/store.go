@@ -0,0 +1,113 @@
+package main
+
+import (
+	"example.com/deck"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// where decks are kept between restarts. Games, leases and idempotency keys
+// only ever live in memory
+type Store interface {
+	Load() (map[uuid.UUID]deck.Deck, error)
+	Save(decks map[uuid.UUID]deck.Deck) error
+	// whether decks could be saved right now
+	Ping() error
+}
+
+// decks are gone once the server stops
+type memoryStore struct{}
+
+func (memoryStore) Load() (map[uuid.UUID]deck.Deck, error) {
+	return map[uuid.UUID]deck.Deck{}, nil
+}
+
+func (memoryStore) Save(decks map[uuid.UUID]deck.Deck) error {
+	return nil
+}
+
+func (memoryStore) Ping() error {
+	return nil
+}
+
+// decks written down as the snapshots /v1/decks/{guid}/export answers with,
+// keyed by guid
+type fileStore struct {
+	path string
+}
+
+func (s *fileStore) Load() (map[uuid.UUID]deck.Deck, error) {
+	return deck.ReadSnapshots(s.path)
+}
+
+func (s *fileStore) Save(decks map[uuid.UUID]deck.Deck) error {
+	return deck.WriteSnapshots(s.path, decks)
+}
+
+// the directory must be writable for the temporary file Save goes through
+func (s *fileStore) Ping() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), ".ping-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// replaces the decks with those in the store. Meant to be called before
+// serving anything
+func (ctx *HandlerContext) Load() error {
+	decks, err := ctx.store.Load()
+	if err != nil {
+		ctx.metrics.storeFailed()
+		return err
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	*ctx.decks = decks
+	return nil
+}
+
+func (ctx *HandlerContext) Flush() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.flushErr = ctx.store.Save(*ctx.decks)
+	if ctx.flushErr != nil {
+		ctx.metrics.storeFailed()
+	}
+	return ctx.flushErr
+}
+
+// flushes the decks every interval and once more when stopped, so that a
+// crash loses at most interval's worth of changes
+func (ctx *HandlerContext) StartFlusher(interval time.Duration) (stop func() error) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := ctx.Flush(); err != nil {
+					ctx.logger.Error("Failed to flush decks", "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+		return ctx.Flush()
+	}
+}
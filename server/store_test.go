@@ -0,0 +1,69 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decks", "decks.json")
+
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	ctx.store = &fileStore{path}
+	if err := ctx.Load(); err != nil || len(*ctx.decks) != 0 {
+		t.Fatalf("Expected to start without decks, found %v (%v)", *ctx.decks, err)
+	}
+
+	created := decodeCreatedDeck(t, fetch(t, ctx, http.MethodPost, "/v1/decks?cards=AS,KD,QH&player=alice"))
+	fetch(t, ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?pile=hand&player=alice", created.Guid))
+
+	t.Run("flushes decks as snapshots", func(t *testing.T) {
+		if err := ctx.Flush(); err != nil {
+			t.Fatalf("Expected to flush, found %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected the decks to be written down, found %v", err)
+		}
+	})
+
+	t.Run("restores decks on load", func(t *testing.T) {
+		restored := make(map[uuid.UUID]deck.Deck)
+		other := NewHandlerContext(&restored)
+		other.store = &fileStore{path}
+		if err := other.Load(); err != nil {
+			t.Fatalf("Expected to load, found %v", err)
+		}
+
+		opened, err := open(t, other, fmt.Sprintf("/v1/decks/%s?player=alice", created.Guid))
+		if err != nil || opened.RemainingCardCount != 2 || opened.Owner != "alice" || len(opened.Piles["hand"].Cards) != 1 {
+			t.Errorf("Expected alice's deck as she left it, found %v (%v)", opened, err)
+		}
+	})
+
+	t.Run("flushes once more when stopped", func(t *testing.T) {
+		stop := ctx.StartFlusher(time.Hour)
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/v1/decks/%s/draws?player=alice", created.Guid))
+		if err := stop(); err != nil {
+			t.Fatalf("Expected the last flush to succeed, found %v", err)
+		}
+
+		loaded, _ := (&fileStore{path}).Load()
+		if d := loaded[created.Guid]; d.RemainingCardCount() != 1 {
+			t.Errorf("Expected the last draw to be flushed, found %v cards", d.RemainingCardCount())
+		}
+	})
+
+	t.Run("refuses a corrupt file", func(t *testing.T) {
+		os.WriteFile(path, []byte("{"), 0o644)
+		if _, err := (&fileStore{path}).Load(); err == nil {
+			t.Errorf("Expected a corrupt file to be refused")
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// writes a span per request for local debugging, one JSON object per line
+// in the shape the OpenTelemetry stdout exporter writes them. Traces are
+// continued from the W3C traceparent header when there is one. A nil Tracer
+// traces nothing
+type Tracer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewTracer(out io.Writer) *Tracer {
+	return &Tracer{out: out}
+}
+
+type span struct {
+	Name                 string
+	SpanContext          spanContext
+	Parent               spanContext
+	SpanKind             int
+	StartTime            time.Time
+	EndTime              time.Time
+	Attributes           []spanAttribute
+	Status               spanStatus
+	InstrumentationScope spanScope
+}
+
+type spanContext struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+	TraceState string
+	Remote     bool
+}
+
+type spanAttribute struct {
+	Key   string
+	Value spanValue
+}
+
+type spanValue struct {
+	Type  string
+	Value any
+}
+
+type spanStatus struct {
+	Code        string
+	Description string
+}
+
+type spanScope struct {
+	Name string
+}
+
+// as OpenTelemetry numbers them
+const spanKindServer = 2
+
+// version-traceid-parentid-flags, as in 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+var traceparent = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (t *Tracer) start(r *http.Request) *span {
+	if t == nil {
+		return nil
+	}
+
+	s := &span{
+		Name:                 r.Pattern,
+		SpanContext:          spanContext{TraceID: randomID(16), SpanID: randomID(8), TraceFlags: "01"},
+		SpanKind:             spanKindServer,
+		StartTime:            time.Now(),
+		InstrumentationScope: spanScope{"example.com/server"},
+	}
+	if match := traceparent.FindStringSubmatch(r.Header.Get("traceparent")); match != nil &&
+		strings.Trim(match[1], "0") != "" && strings.Trim(match[2], "0") != "" {
+		s.Parent = spanContext{TraceID: match[1], SpanID: match[2], TraceFlags: match[3], Remote: true}
+		s.SpanContext.TraceID = match[1]
+		s.SpanContext.TraceFlags = match[3]
+	}
+
+	route := r.Pattern
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		route = path
+	}
+	s.attribute("http.request.method", r.Method)
+	s.attribute("http.route", route)
+	s.attribute("url.path", r.URL.Path)
+	return s
+}
+
+func (s *span) attribute(key string, value any) {
+	kind := "STRING"
+	switch value.(type) {
+	case int, int64:
+		kind = "INT64"
+	case bool:
+		kind = "BOOL"
+	case float64:
+		kind = "FLOAT64"
+	}
+	s.Attributes = append(s.Attributes, spanAttribute{key, spanValue{kind, value}})
+}
+
+// writes s once the request is answered with status, along with what the
+// handler added to its log
+func (t *Tracer) end(s *span, operation string, requestID string, status int, attrs []slog.Attr) {
+	s.EndTime = time.Now()
+	s.attribute("http.response.status_code", status)
+	s.attribute("cards.operation", operation)
+	s.attribute("cards.request_id", requestID)
+	for _, attr := range attrs {
+		s.attribute("cards."+attr.Key, attr.Value.String())
+	}
+	s.Status.Code = "Unset"
+	if status >= http.StatusInternalServerError {
+		s.Status = spanStatus{"Error", http.StatusText(status)}
+	}
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.out.Write(append(line, '\n'))
+}
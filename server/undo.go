@@ -0,0 +1,77 @@
+package main
+
+import (
+	"example.com/deck"
+	"example.com/game"
+	"net/http"
+	"strconv"
+)
+
+// POST /v1/decks/{guid}/undo?count=2 takes back the last 2 operations (1 by default).
+// Decks created with ?rules={game type} are subject to that game's rules,
+// which may forbid undoing operations that revealed cards
+func (ctx *HandlerContext) Undo(w http.ResponseWriter, r *http.Request) {
+	ctx.rewind(w, r, func(d *deck.Deck, count int) ([]deck.Event, error) {
+		policy, err := undoPolicy(d)
+		if err != nil {
+			return []deck.Event{}, err
+		}
+		return d.Undo(requestingPlayer(r), count, policy)
+	})
+}
+
+// POST /v1/decks/{guid}/redo?count=2 redoes the last 2 undone operations (1 by default)
+func (ctx *HandlerContext) Redo(w http.ResponseWriter, r *http.Request) {
+	ctx.rewind(w, r, func(d *deck.Deck, count int) ([]deck.Event, error) {
+		return d.Redo(requestingPlayer(r), count)
+	})
+}
+
+func undoPolicy(d *deck.Deck) (deck.UndoPolicy, error) {
+	if d.Rules == "" {
+		return nil, nil
+	}
+	return game.UndoPolicyFor(d.Rules)
+}
+
+func (ctx *HandlerContext) rewind(w http.ResponseWriter, r *http.Request, rewind func(*deck.Deck, int) ([]deck.Event, error)) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	d, err := retrieveDeck(ctx, r)
+	if err != nil {
+		writeError(w, retrieveStatus(err), err)
+		return
+	}
+
+	if err := authorize(r, &d); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if err := checkVersion(r, &d); err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	count := 1
+	if param := r.URL.Query().Get("count"); param != "" {
+		count, err = strconv.Atoi(param)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &InputError{"count", param, ErrInvalidParameter})
+			return
+		}
+	}
+
+	if _, err := rewind(&d, count); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	(*ctx.decks)[d.Guid] = d
+	player := requestingPlayer(r)
+	ctx.broker.Publish(fromDeckEvent(&d, d.History[len(d.History)-1]))
+
+	setETag(w, &d)
+	respond(w, r, http.StatusOK, intoOpenDeck(d, player))
+}
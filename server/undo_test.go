@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+
+	t.Run("undoes and redoes draws", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2", created.Guid))
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=3", created.Guid))
+
+		w := fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/undo/%s?count=2", created.Guid))
+		if w.Code != http.StatusOK || len(decks[created.Guid].Cards) != 52 {
+			t.Errorf("Expected both draws to be undone, found %v", w.Body.String())
+		}
+
+		w = fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/redo/%s", created.Guid))
+		if w.Code != http.StatusOK || len(decks[created.Guid].Cards) != 50 {
+			t.Errorf("Expected the first draw to be redone, found %v", w.Body.String())
+		}
+	})
+
+	t.Run("keeps undone events in the history", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		draw(t, ctx, fmt.Sprintf("/draw/%s?count=2", created.Guid))
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/undo/%s", created.Guid))
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/shuffle/%s", created.Guid))
+
+		var events []Event
+		json.Unmarshal([]byte(fetch(t, ctx, http.MethodGet, fmt.Sprintf("/v1/decks/%s/history", created.Guid))), &events)
+		types := []string{}
+		for i, e := range events {
+			if e.Seq != i+1 {
+				t.Errorf("Expected seq %d, found %v", i+1, e)
+			}
+			types = append(types, e.Type)
+		}
+		if !slices.Equal(types, []string{"created", "drawn", "undone", "shuffled"}) || !slices.Equal(events[2].Targets, []int{2}) {
+			t.Errorf("Expected the draw and its undoing in the history, found %v", events)
+		}
+	})
+
+	t.Run("fails to undo more than happened", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create")
+		w := fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/undo/%s", created.Guid))
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected 409 since nothing happened to the deck, found %v", w.Code)
+		}
+	})
+
+	t.Run("game rules forbid undoing revealed cards", func(t *testing.T) {
+		created, _ := create(t, ctx, "/create?rules=highcard&visibility=facedown")
+		fetch(t, ctx, http.MethodPost, fmt.Sprintf("/shuffle/%s", created.Guid))
+
+		w := fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/undo/%s", created.Guid))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected a face down shuffle to be undone, found %v", w.Body.String())
+		}
+
+		draw(t, ctx, fmt.Sprintf("/draw/%s?player=alice", created.Guid))
+		w = fetchResponse(ctx, http.MethodPost, fmt.Sprintf("/undo/%s", created.Guid))
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected 409 since alice saw the card she drew, found %v", w.Code)
+		}
+	})
+
+	t.Run("fails to create deck governed by unknown rules", func(t *testing.T) {
+		_, err := create(t, ctx, "/create?rules=nope")
+		if err == nil {
+			t.Errorf("Expected err due to unknown rules")
+		}
+	})
+}
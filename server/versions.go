@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"example.com/deck"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrVersionMismatch = errors.New("Deck has changed")
+
+// the version alone is enough since an ETag only ever describes one deck
+func etag(d *deck.Deck) string {
+	return fmt.Sprintf(`"%d"`, d.Version)
+}
+
+func setETag(w http.ResponseWriter, d *deck.Deck) {
+	w.Header().Set("ETag", etag(d))
+}
+
+// clients that send back the ETag they last saw in If-Match only get to
+// change the deck if nobody changed it in the meantime. Without If-Match
+// the last one to write wins as before
+func checkVersion(r *http.Request, d *deck.Deck) error {
+	return matchVersion(r.Header.Get("If-Match"), d)
+}
+
+func matchVersion(header string, d *deck.Deck) error {
+	if header == "" {
+		return nil
+	}
+
+	current := etag(d)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: deck %v is at version %d", ErrVersionMismatch, d.Guid, d.Version)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"example.com/deck"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	decks := make(map[uuid.UUID]deck.Deck)
+	ctx := NewHandlerContext(&decks)
+	created, _ := create(t, ctx, "/create?player=alice")
+	deckUrl := fmt.Sprintf("/v1/decks/%s?player=alice", created.Guid)
+	drawUrl := fmt.Sprintf("/v1/decks/%s/draws?player=alice", created.Guid)
+
+	t.Run("returns version as ETag", func(t *testing.T) {
+		w := fetchResponse(ctx, http.MethodGet, deckUrl)
+		if etag := w.Header().Get("ETag"); etag != `"1"` {
+			t.Errorf("Expected a freshly created deck to be at version 1, found %v", etag)
+		}
+	})
+
+	t.Run("changes go through when the version matches", func(t *testing.T) {
+		etag := fetchResponse(ctx, http.MethodGet, deckUrl).Header().Get("ETag")
+		w := sendRequest(ctx, http.MethodPost, drawUrl, map[string]string{"If-Match": etag}, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected draw to go through, found %v", w.Code)
+		}
+		if next := w.Header().Get("ETag"); next == etag {
+			t.Errorf("Expected the draw to bump the version past %v", etag)
+		}
+	})
+
+	t.Run("stale clients get 412", func(t *testing.T) {
+		etag := fetchResponse(ctx, http.MethodGet, deckUrl).Header().Get("ETag")
+		sendRequest(ctx, http.MethodPost, drawUrl, map[string]string{"If-Match": etag}, "")
+
+		remaining := len(decks[created.Guid].Cards)
+		urls := []string{
+			drawUrl,
+			fmt.Sprintf("/v1/decks/%s/shuffles?player=alice", created.Guid),
+			fmt.Sprintf("/v1/decks/%s/shares?with=bob&player=alice", created.Guid),
+			fmt.Sprintf("/v1/decks/%s/undo?player=alice", created.Guid),
+		}
+		for _, url := range urls {
+			w := sendRequest(ctx, http.MethodPost, url, map[string]string{"If-Match": etag}, "")
+			if w.Code != http.StatusPreconditionFailed {
+				t.Errorf("Expected 412 for %v, found %v", url, w.Code)
+			}
+		}
+		if n := len(decks[created.Guid].Cards); n != remaining {
+			t.Errorf("Expected stale requests to leave the deck alone, found %v cards", n)
+		}
+	})
+
+	t.Run("matches any of several ETags or a wildcard", func(t *testing.T) {
+		etag := fetchResponse(ctx, http.MethodGet, deckUrl).Header().Get("ETag")
+		for _, header := range []string{`"0", ` + etag, "*"} {
+			w := sendRequest(ctx, http.MethodPost, drawUrl, map[string]string{"If-Match": header}, "")
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected If-Match %v to go through, found %v", header, w.Code)
+			}
+			etag = w.Header().Get("ETag")
+		}
+	})
+}
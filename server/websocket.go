@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	writeTimeout = 10 * time.Second
+	pongTimeout  = 60 * time.Second
+	pingInterval = pongTimeout * 9 / 10
+)
+
+// authentication does not rely on cookies so any origin is fine here, even
+// those browsers are kept from calling the rest of the API from (see cors)
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// GET /v1/decks/{guid}/ws?since=12 streams the events of a deck or a game. since is
+// the seq of the last event the client saw and is used to resume after a
+// disconnect. Clients that fall too far behind are disconnected and should
+// reconnect with since, or refetch everything if told the events are gone
+func (ctx *HandlerContext) WebSocket(w http.ResponseWriter, r *http.Request) {
+	since := 0
+	if param := r.URL.Query().Get("since"); param != "" {
+		var err error
+		since, err = strconv.Atoi(param)
+		if err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, &InputError{"since", param, ErrInvalidParameter})
+			return
+		}
+	}
+
+	sub, ok := ctx.subscribe(w, r, since)
+	if !ok {
+		return
+	}
+	defer ctx.broker.Unsubscribe(sub)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	go discardIncoming(conn)
+	pumpEvents(conn, sub)
+}
+
+// shared by every streaming endpoint. Replies to the client when the
+// subscription can't be made
+func (ctx *HandlerContext) subscribe(w http.ResponseWriter, r *http.Request, since int) (*Subscriber, bool) {
+	guid, err := parseGuid(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if status, err := ctx.authorizeEvents(requestingPlayer(r), guid); err != nil {
+		writeError(w, status, err)
+		return nil, false
+	}
+
+	sub, err := ctx.broker.Subscribe(guid, requestingPlayer(r), since)
+	if errors.Is(err, ErrEventsGone) {
+		writeError(w, http.StatusGone, err)
+		return nil, false
+	}
+	if errors.Is(err, ErrShuttingDown) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return sub, true
+}
+
+// clients don't send us anything but reading is what processes pongs and
+// notices the client went away
+func discardIncoming(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
+
+func pumpEvents(conn *websocket.Conn, sub *Subscriber) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	lastSeq := 0
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				reason := fmt.Sprintf("too slow, resume with since=%d", lastSeq)
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, reason)
+				conn.WriteMessage(websocket.CloseMessage, msg)
+				return
+			}
+			if err := conn.WriteJSON(e.For(sub.Player)); err != nil {
+				return
+			}
+			lastSeq = e.Seq
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// decks can only be watched by those who may access them. Anyone may watch
+// a game as views are filtered for whoever is watching
+func (ctx *HandlerContext) authorizeEvents(player string, guid uuid.UUID) (int, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if d, ok := (*ctx.decks)[guid]; ok {
+		if err := authorizePlayer(player, &d); err != nil {
+			return http.StatusForbidden, err
+		}
+		return http.StatusOK, nil
+	}
+	if _, ok := (*ctx.sessions)[guid]; ok {
+		return http.StatusOK, nil
+	}
+	if ctx.expired(guid) {
+		return http.StatusGone, fmt.Errorf("%w: %v", ErrDeckExpired, guid)
+	}
+	return http.StatusNotFound, fmt.Errorf("%w: %v", ErrDeckNotFound, guid)
+}